@@ -0,0 +1,45 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spilliams/goraffe/pkg/tree"
+
+	"github.com/spf13/cobra"
+)
+
+func newCacheCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Manage the package cache",
+		Long: `Manage the package cache.
+
+Goraffe remembers the imports of every package it loads, so that later runs only
+have to re-read the packages whose files changed. The cache lives in the
+"goraffe" directory under your user cache directory.`,
+	}
+
+	cmd.AddCommand(newCacheCleanCmd())
+
+	return cmd
+}
+
+func newCacheCleanCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "clean",
+		Short: "Removes everything from the package cache",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir, err := tree.DefaultCacheDir()
+			if err != nil {
+				return err
+			}
+			if err := os.RemoveAll(dir); err != nil {
+				return err
+			}
+			fmt.Printf("Removed %s\n", dir)
+			return nil
+		},
+	}
+}
@@ -0,0 +1,128 @@
+package cli
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/spilliams/goraffe/pkg/calltree"
+	"github.com/spilliams/goraffe/pkg/filter"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// the names of the flags
+const (
+	rootFlag = "root"
+	algoFlag = "algo"
+)
+
+var calltreeFlags struct {
+	grow      int
+	keeps     []string
+	branches  []string
+	roots     []string
+	algorithm string
+	format    string
+	legend    bool
+	output    string
+	load      loadOptions
+}
+
+func newCalltreeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "calltree <parent directory> <package> <function>",
+		Args:    cobra.ExactArgs(3),
+		Example: "goraffe calltree github.com/spilliams/goraffe pkg/tree '(*Tree).Prune' --tests",
+		Short:   "Visualize the calls leading to a function",
+		Long: `Visualize the calls leading to a function.
+
+This command type-checks the packages in the parent directory, builds their call
+graph, and traces the callers of the given function back until they run out:
+at main, init, functions nothing calls, and (with --tests) test files, whose
+calls all lead from a single "test" root. Callers outside the parent directory
+(like cobra or net/http) are followed but not drawn, so a callback handed to
+them is drawn as called by the function that handed it over. The
+function is named like "NewTree", "Tree.Prune" or "(*Tree).Prune", and its
+package with or without the parent directory prefix. The package may be outside
+the parent directory, like github.com/sirupsen/logrus.
+
+The result is drawn like the output of ` + "`imports`" + `, with functions for
+packages and calls for imports. --root picks which of the roots to trace from,
+and --keep, --grow and --branch trim the tree the same way they do for
+` + "`imports`" + `.
+
+Dynamic calls (through interfaces and function values) are resolved with
+--algo: "vta" follows the values that can actually reach each call, and "cha"
+assumes any method or function of the right signature can.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkLegend(calltreeFlags.legend, calltreeFlags.format); err != nil {
+				return err
+			}
+			o := calltreeFlags.load
+			graph, err := calltree.Trace(args[0], args[1], args[2], calltree.Options{
+				Dir:       o.dir,
+				Context:   o.buildContext(o.goos, o.goarch),
+				Tests:     o.tests,
+				Algorithm: calltreeFlags.algorithm,
+			})
+			if err != nil {
+				return err
+			}
+
+			roots := graph.Roots()
+			if len(roots) == 0 {
+				return fmt.Errorf("nothing in %s calls %s", args[0], graph.Target())
+			}
+			if len(calltreeFlags.roots) > 0 {
+				f, err := filter.New(calltreeFlags.roots, nil)
+				if err != nil {
+					return err
+				}
+				prefix := path.Clean(args[0]) + "/"
+				picked := []string{}
+				for _, root := range roots {
+					if f.Include(root, strings.TrimPrefix(root, prefix)) {
+						picked = append(picked, root)
+					}
+				}
+				if len(picked) == 0 {
+					return fmt.Errorf("none of the roots match --%s; they are:\n%s", rootFlag, strings.Join(roots, "\n"))
+				}
+				roots = picked
+			}
+			logrus.Infof("Tracing from %d roots", len(roots))
+
+			callTree, err := graph.Tree(roots)
+			if err != nil {
+				return err
+			}
+			if err := trim(callTree, calltreeFlags.keeps, calltreeFlags.branches, calltreeFlags.grow); err != nil {
+				return err
+			}
+
+			logrus.Debug(callTree)
+
+			callTree.SetLegend(calltreeFlags.legend)
+			out, err := formatTree(callTree, calltreeFlags.format)
+			if err != nil {
+				return err
+			}
+			return writeGraph(out, calltreeFlags.format, calltreeFlags.output)
+		},
+	}
+
+	cmd.Flags().IntVar(&calltreeFlags.grow, growFlag, 1, "How far to \"grow\" the tree away from any kept\nfunctions. Use with --"+keepFlag+".")
+	cmd.Flags().StringArrayVar(&calltreeFlags.keeps, keepFlag, []string{}, "Designate some functions to \"keep\", and prune away\nthe rest.")
+	cmd.Flags().StringArrayVar(&calltreeFlags.branches, branchFlag, []string{}, "Designate a function to branch to--the tree will include\nthe roots and this branch, and just the calls in between.")
+	cmd.Flags().StringArrayVar(&calltreeFlags.roots, rootFlag, []string{}, "Only trace from the roots matching this pattern (see\n--"+includeFlag+" on imports), e.g. cmd/... or test.")
+	cmd.Flags().StringVar(&calltreeFlags.algorithm, algoFlag, calltree.VTA, fmt.Sprintf("The call graph algorithm, one of: %s.", strings.Join(calltree.Algorithms, ", ")))
+	cmd.Flags().BoolVar(&calltreeFlags.legend, legendFlag, false, "Whether to add a legend explaining the colors and\nlabels to the DOT output.")
+	cmd.Flags().StringVar(&calltreeFlags.format, formatFlag, dotFormat, formatUsage())
+	cmd.Flags().StringVarP(&calltreeFlags.output, outputFlag, "o", "", "Render the graph to this file instead of printing DOT\n(see --"+outputFlag+" on imports).")
+	cmd.Flags().BoolVar(&calltreeFlags.load.tests, testsFlag, false, "Whether to load Go test files. Calls from them lead\nfrom a single \"test\" root.")
+	calltreeFlags.load.addContextFlags(cmd.Flags())
+
+	return cmd
+}
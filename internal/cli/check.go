@@ -0,0 +1,143 @@
+package cli
+
+import (
+	"fmt"
+	"go/build"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spilliams/goraffe/pkg/rules"
+	"github.com/spilliams/goraffe/pkg/tree"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// the names of the flags
+const (
+	rulesFlag  = "rules"
+	reportFlag = "report"
+)
+
+// the formats a check report can be written in
+const (
+	textReport  = "text"
+	jsonReport  = "json"
+	sarifReport = "sarif"
+)
+
+var checkFlags struct {
+	rules  string
+	report string
+	load   loadOptions
+}
+
+func newCheckCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "check <parent directory> <root packages>",
+		Args:    validateImportsArgs,
+		Example: "goraffe check --rules goraffe.rules.json github.com/spilliams/goraffe cmd/goraffe",
+		Short:   "Check package imports against architecture rules",
+		Long: `Check package imports against architecture rules.
+
+This command loads a tree the same way ` + "`imports`" + ` does, then checks every import
+in it against the rules file. If any import breaks a rule, it reports them and
+exits non-zero, which makes it suitable for CI.
+
+The rules file is JSON, and may declare layers (listed from the top down, each
+of which may only import its own layer or lower ones), forbidden imports, and
+allowed imports (which are exceptions to the other rules):
+
+{
+  "layers": [
+    {"name": "commands", "packages": ["cmd/..."]},
+    {"name": "cli", "packages": ["internal/..."]},
+    {"name": "library", "packages": ["pkg/..."]}
+  ],
+  "forbidden": [
+    {"from": "pkg/...", "to": "internal/...", "reason": "pkg is public"}
+  ],
+  "allowed": [
+    {"from": "pkg/legacy", "to": "internal/cli"}
+  ]
+}
+
+Patterns are matched against package names with and without the parent
+directory prefix, like those of --` + includeFlag + `: "..." matches anything, and
+"pkg/..." matches pkg too; "*" and "**" are globs; and /expr/ is a regular
+expression.
+
+The report is plain text by default. --report json writes a JSON array of
+violations, and --report sarif writes a SARIF log for annotating pull requests.
+File names in reports are relative to the working directory, even with
+--at-ref.`,
+		// the report is the output; don't muddle it with usage or errors
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			r, err := rules.Load(checkFlags.rules)
+			if err != nil {
+				return err
+			}
+
+			wd, err := os.Getwd()
+			if err != nil {
+				return err
+			}
+			dir, err := filepath.Abs(checkFlags.load.dir)
+			if err != nil {
+				return err
+			}
+			wdFromDir, err := filepath.Rel(dir, wd)
+			if err != nil {
+				return err
+			}
+			// with --at-ref, the files are in a worktree, so the report is
+			// made relative to where the working directory would be in it
+			var base string
+			checkFlags.load.inspect = func(t *tree.Tree, dir string, ctx *build.Context) (*tree.Tree, error) {
+				dir, err := filepath.Abs(dir)
+				if err != nil {
+					return nil, err
+				}
+				base = filepath.Join(dir, wdFromDir)
+				return t, nil
+			}
+
+			importTree, err := checkFlags.load.load(args[0], args[1:])
+			if err != nil {
+				return err
+			}
+
+			violations := r.Evaluate(importTree)
+
+			switch checkFlags.report {
+			case textReport:
+				err = rules.WriteText(os.Stdout, violations, base)
+			case jsonReport:
+				err = rules.WriteJSON(os.Stdout, violations, base)
+			case sarifReport:
+				err = rules.WriteSARIF(os.Stdout, violations, base)
+			default:
+				err = fmt.Errorf("unknown report format %q, must be one of: %s", checkFlags.report, strings.Join([]string{textReport, jsonReport, sarifReport}, ", "))
+			}
+			if err != nil {
+				return err
+			}
+
+			if len(violations) > 0 {
+				logrus.Errorf("%d imports break the rules in %s", len(violations), checkFlags.rules)
+				return exitError{code: 1}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&checkFlags.rules, rulesFlag, "goraffe.rules.json", "The rules file to check against.")
+	cmd.Flags().StringVar(&checkFlags.report, reportFlag, textReport, "The report format, one of: "+strings.Join([]string{textReport, jsonReport, sarifReport}, ", ")+".")
+	checkFlags.load.addFlags(cmd.Flags())
+	checkFlags.load.addRefFlag(cmd.Flags())
+
+	return cmd
+}
@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var cyclesFlags struct {
+	load loadOptions
+}
+
+func newCyclesCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "cycles <parent directory> <root packages>",
+		Args:    validateImportsArgs,
+		Example: "goraffe cycles --tests github.com/spilliams/goraffe cmd/goraffe",
+		Short:   "List import cycles",
+		Long: `List import cycles.
+
+The compiler forbids import cycles among production packages, but once test
+imports are included (see --tests), a package's own test files may import a
+package that imports it back, which ` + "`go test`" + ` rejects too. (External test
+packages can't be part of a cycle, since nothing imports them.)
+
+This command loads a tree the same way ` + "`imports`" + ` does, then lists each
+strongly-connected component of it: each group of packages that can all reach
+each other by following imports. Under each component it prints the shortest
+cycle through the component's first package.
+
+In the DOT output of ` + "`imports`" + `, the edges within these components are red.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			importTree, err := cyclesFlags.load.load(args[0], args[1:])
+			if err != nil {
+				return err
+			}
+
+			components := importTree.SCCs()
+			cycles := importTree.Cycles()
+			if len(components) == 0 {
+				fmt.Println("no import cycles")
+				return nil
+			}
+
+			for i, component := range components {
+				names := make([]string, 0, len(component))
+				for _, name := range component {
+					names = append(names, importTree.DisplayName(name))
+				}
+				fmt.Printf("component %d (%d packages): %s\n", i+1, len(names), strings.Join(names, ", "))
+
+				cycle := make([]string, 0, len(cycles[i]))
+				for _, name := range cycles[i] {
+					cycle = append(cycle, importTree.DisplayName(name))
+				}
+				fmt.Printf("  %s\n", strings.Join(cycle, " -> "))
+			}
+
+			return nil
+		},
+	}
+
+	cyclesFlags.load.addFlags(cmd.Flags())
+	cyclesFlags.load.addRefFlag(cmd.Flags())
+
+	return cmd
+}
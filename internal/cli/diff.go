@@ -0,0 +1,166 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spilliams/goraffe/pkg/tree"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// the output formats a diff can be written in
+const (
+	textFormat     = "text"
+	markdownFormat = "markdown"
+)
+
+var diffFormats = []string{textFormat, markdownFormat, dotFormat}
+
+// the names of the flags
+const (
+	baseFlag = "base"
+	headFlag = "head"
+)
+
+var diffFlags struct {
+	base   string
+	head   string
+	format string
+	load   loadOptions
+}
+
+func newDiffCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "diff [<base> <head>] [<parent directory> <root packages>]",
+		Args:    validateDiffArgs,
+		Example: "goraffe diff ../goraffe-main . github.com/spilliams/goraffe cmd/goraffe\ngoraffe diff before.json after.json\ngoraffe diff --base origin/main github.com/spilliams/goraffe cmd/goraffe",
+		Short:   "Show which packages and imports were added or removed",
+		Long: `Show which packages and imports were added or removed.
+
+The base and head are each either a directory (e.g. a checkout or worktree of
+the code at some revision) or a JSON snapshot written by
+` + "`goraffe imports --format json`" + `. Directories are loaded the same way
+` + "`imports`" + ` would load them with --dir, so the parent directory and root
+packages must be given unless both are snapshots. Directories must be inside a
+Go module.
+
+Alternatively, name git revisions with --base and --head, and give only the
+parent directory and root packages. Each revision is checked out into a
+temporary worktree of the repository containing --dir. Without --head, the
+head is the code in --dir as it is on disk.
+
+With --format text (the default), each change is printed on its own line, "+"
+for additions and "-" for removals, followed by a summary. With --format
+markdown, the added and removed imports are summed up in a sentence and
+listed, ready to comment on a pull request. With --format dot, the trees are
+drawn together: added packages and imports are green, and removed ones are
+dashed and red.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var base, head *tree.Tree
+			var err error
+			if diffFlags.base != "" {
+				base, err = loadDiffRef(diffFlags.base, args)
+				if err != nil {
+					return err
+				}
+				head, err = loadDiffRef(diffFlags.head, args)
+			} else {
+				base, err = loadDiffTree(args[0], args[2:])
+				if err != nil {
+					return err
+				}
+				head, err = loadDiffTree(args[1], args[2:])
+			}
+			if err != nil {
+				return err
+			}
+
+			diff := tree.NewDiff(base, head)
+			switch diffFlags.format {
+			case textFormat:
+				fmt.Println(diff)
+			case markdownFormat:
+				fmt.Print(diff.Markdown())
+			case dotFormat:
+				graph, err := diff.Tree().Graphviz()
+				if err != nil {
+					return err
+				}
+				fmt.Println(graph)
+				logrus.Info(diff.Summary())
+			default:
+				return fmt.Errorf("unknown format %q, must be one of: %s", diffFlags.format, strings.Join(diffFormats, ", "))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&diffFlags.base, baseFlag, "", "The git revision to compare from. Replaces the <base>\nand <head> arguments.")
+	cmd.Flags().StringVar(&diffFlags.head, headFlag, "", "The git revision to compare to. Use with --"+baseFlag+".\nDefaults to the code on disk.")
+	cmd.Flags().StringVar(&diffFlags.format, formatFlag, textFormat, fmt.Sprintf("The output format, one of: %s.", strings.Join(diffFormats, ", ")))
+	diffFlags.load.addFlags(cmd.Flags())
+
+	return cmd
+}
+
+func validateDiffArgs(cmd *cobra.Command, args []string) error {
+	if diffFlags.base != "" {
+		return validateImportsArgs(cmd, args)
+	}
+	if diffFlags.head != "" {
+		return fmt.Errorf("--%s must be used with --%s", headFlag, baseFlag)
+	}
+	if len(args) != 2 && len(args) < 4 {
+		return fmt.Errorf("must provide a base and a head, followed by the parent directory and at least one root package (unless both are JSON snapshots)")
+	}
+	return nil
+}
+
+// loadDiffRef loads a tree from the code at the given git revision, or from the
+// code on disk if the revision is empty.
+func loadDiffRef(rev string, args []string) (*tree.Tree, error) {
+	opts := diffFlags.load
+	opts.atRef = rev
+	return opts.load(args[0], args[1:])
+}
+
+// loadDiffTree reads a tree from the named JSON snapshot, or loads it from the
+// named directory.
+func loadDiffTree(name string, args []string) (*tree.Tree, error) {
+	info, err := os.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		t, err := tree.ReadJSON(f)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		return t, nil
+	}
+
+	if len(args) < 2 {
+		return nil, fmt.Errorf("%s is a directory, so the parent directory and root packages must be given", name)
+	}
+	// outside a module, packages are resolved from GOPATH, wherever the
+	// directory is, so both sides would be the same code
+	gomod, err := tree.ModuleFile(name)
+	if err != nil {
+		return nil, err
+	}
+	if gomod == "" {
+		return nil, fmt.Errorf("%s is not inside a Go module; diff can only load directories in module mode", name)
+	}
+	opts := diffFlags.load
+	opts.dir = name
+	logrus.Infof("Loading %s", name)
+	return opts.load(args[0], args[1:])
+}
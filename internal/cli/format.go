@@ -0,0 +1,45 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spilliams/goraffe/pkg/tree"
+)
+
+// the output formats a tree can be written in
+const (
+	dotFormat     = "dot"
+	jsonFormat    = "json"
+	mermaidFormat = "mermaid"
+)
+
+var treeFormats = []string{dotFormat, jsonFormat, mermaidFormat}
+
+const formatFlag = "format"
+
+func formatUsage() string {
+	return fmt.Sprintf("The output format, one of: %s.", strings.Join(treeFormats, ", "))
+}
+
+// formatTree renders the tree in the given format.
+func formatTree(t *tree.Tree, format string) (string, error) {
+	switch format {
+	case dotFormat:
+		return t.Graphviz()
+	case jsonFormat:
+		return t.JSON()
+	case mermaidFormat:
+		return t.Mermaid()
+	}
+	return "", fmt.Errorf("unknown format %q, must be one of: %s", format, strings.Join(treeFormats, ", "))
+}
+
+// checkLegend returns an error if a legend was asked for in a format that
+// can't show one.
+func checkLegend(legend bool, format string) error {
+	if legend && format != dotFormat {
+		return fmt.Errorf("--%s is drawn in the DOT output, so it can't be used with --%s %s", legendFlag, formatFlag, format)
+	}
+	return nil
+}
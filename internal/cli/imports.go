@@ -2,7 +2,11 @@ package cli
 
 import (
 	"fmt"
+	"go/build"
+	"os"
+	"strings"
 
+	"github.com/spilliams/goraffe/pkg/render"
 	"github.com/spilliams/goraffe/pkg/tree"
 
 	"github.com/sirupsen/logrus"
@@ -11,19 +15,40 @@ import (
 
 // the names of the flags
 const (
-	growFlag   = "grow"
-	keepFlag   = "keep"
-	testsFlag  = "tests"
-	extsFlag   = "exts"
-	branchFlag = "branch"
+	growFlag    = "grow"
+	keepFlag    = "keep"
+	branchFlag  = "branch"
+	legendFlag  = "legend"
+	clusterFlag = "cluster"
+	outputFlag  = "output"
+
+	colorByFlag      = "color-by"
+	sizeByFlag       = "size-by"
+	coverprofileFlag = "coverprofile"
+
+	symbolsFlag    = "symbols"
+	edgeDetailFlag = "edge-detail"
+
+	collapseFlag      = "collapse"
+	collapseDepthFlag = "collapse-depth"
 )
 
 var importsFlags struct {
 	grow     int
 	keeps    []string
-	tests    bool
-	exts     bool
 	branches []string
+	format   string
+	legend   bool
+	cluster  bool
+	output   string
+	colorBy  string
+	sizeBy   string
+	coverage string
+	collapse []string
+	depth    int
+	symbols  bool
+	detail   bool
+	load     loadOptions
 }
 
 func newImportsCmd() *cobra.Command {
@@ -40,6 +65,13 @@ output, the name of that parent will be trimmed from the prefix of all the
 package names. The root packages can be named with or without the parent
 directory prefix.
 
+If the working directory (or the one named with --dir) belongs to a Go module,
+packages are resolved through the go command, the same way ` + "`go build`" + ` would
+resolve them there. Otherwise they are looked up in $GOPATH. Packages are loaded
+for the host platform unless you say otherwise with --goos, --goarch and --tags.
+With --platforms, the tree is loaded once per platform and the union is graphed,
+with each import labelled by the platforms it exists on (if not all of them).
+
 The root packages you list as arguments to this command form the start of the
 import-dependency tree. How the tree develops is determined by the other flags
 you provide this command. By default, the roots' dependencies are added
@@ -52,50 +84,71 @@ An example of using the output:
 
 goraffe imports github.com/spilliams/goraffe goraffe | dot -Tsvg > graph.svg
 
-`,
-		RunE: func(cmd *cobra.Command, args []string) error {
-			// importTree is a map of "name" -> ["import", "import", ...]
-			importTree := tree.NewTree(args[0])
+With --format json it outputs a JSON document of the tree's nodes and edges
+instead, for use by other tools. The document's "schemaVersion" field says which
+version of the schema it follows (see the Readme). With --format mermaid it
+outputs a Mermaid flowchart, for embedding in Markdown.
 
-			importTree.SetIncludeTests(importsFlags.tests)
-			importTree.SetIncludeExts(importsFlags.exts)
+With --symbols, the packages are type-checked, and each import is labelled with
+the exported identifiers of the imported package that the importer uses, and
+weighted by how many times it uses them. With --edge-detail, those identifiers
+are listed instead of graphing the tree.
 
-			packages := args[1:]
-			for _, pkg := range packages {
-				if _, err := importTree.AddRecursive(pkg); err != nil {
-					return err
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkLegend(importsFlags.legend, importsFlags.format); err != nil {
+				return err
+			}
+			symbols := importsFlags.symbols || importsFlags.detail
+			if symbols && len(importsFlags.load.platforms) > 0 {
+				return fmt.Errorf("--%s and --%s can't be used with --%s", symbolsFlag, edgeDetailFlag, platformsFlag)
+			}
+			countLines := importsFlags.colorBy == tree.LinesOfCode || importsFlags.sizeBy == tree.LinesOfCode
+			importsFlags.load.inspect = func(t *tree.Tree, dir string, ctx *build.Context) (*tree.Tree, error) {
+				if countLines {
+					t.CountSources()
+				}
+				if symbols {
+					return t, t.LoadSymbols(dir, ctx)
 				}
+				return t, nil
 			}
 
-			for _, name := range importsFlags.keeps {
-				if err := importTree.Keep(name); err != nil {
-					return err
-				}
+			// importTree is a map of "name" -> ["import", "import", ...]
+			importTree, err := importsFlags.load.load(args[0], args[1:])
+			if err != nil {
+				return err
 			}
 
-			// honor either keeps or branch, not both
-			if len(importsFlags.keeps) > 0 {
-				importTree.Grow(importsFlags.grow)
-				importTree.Prune()
-			} else {
-				for _, branch := range importsFlags.branches {
-					if err := importTree.Branch(branch); err != nil {
-						return err
-					}
-				}
-				if len(importsFlags.branches) > 0 {
-					importTree.Prune()
-				}
+			if len(importsFlags.collapse) > 0 || importsFlags.depth > 0 {
+				importTree = importTree.Collapse(importsFlags.collapse, importsFlags.depth)
+			}
+
+			if err := trim(importTree, importsFlags.keeps, importsFlags.branches, importsFlags.grow); err != nil {
+				return err
 			}
 
 			logrus.Debug(importTree)
 
-			graph, err := importTree.Graphviz()
+			if importsFlags.detail {
+				fmt.Print(importTree.EdgeDetail())
+				logrus.Info(importTree.Stats())
+				return nil
+			}
+
+			importTree.SetLegend(importsFlags.legend)
+			importTree.SetCluster(importsFlags.cluster)
+			if err := setMeasures(importTree); err != nil {
+				return err
+			}
+			graph, err := formatTree(importTree, importsFlags.format)
 			if err != nil {
 				return err
 			}
 
-			fmt.Println(graph)
+			if err := writeGraph(graph, importsFlags.format, importsFlags.output); err != nil {
+				return err
+			}
 
 			logrus.Info(importTree.Stats())
 
@@ -104,14 +157,100 @@ goraffe imports github.com/spilliams/goraffe goraffe | dot -Tsvg > graph.svg
 	}
 
 	cmd.Flags().IntVar(&importsFlags.grow, growFlag, 1, "How far to \"grow\" the tree away from any kept\npackages. Use with --"+keepFlag+".")
-	cmd.Flags().BoolVar(&importsFlags.tests, testsFlag, false, "Whether to include imports from Go test files.")
 	cmd.Flags().StringArrayVar(&importsFlags.keeps, keepFlag, []string{}, "Designate some packages to \"keep\", and prune away\nthe rest.")
-	cmd.Flags().BoolVar(&importsFlags.exts, extsFlag, false, "[SLOW] Whether to include packages from outside the\nparent directory.")
 	cmd.Flags().StringArrayVar(&importsFlags.branches, branchFlag, []string{}, "Designate a package to branch to--the tree will include the root and this branch, and just the imports in between.")
+	cmd.Flags().BoolVar(&importsFlags.legend, legendFlag, false, "Whether to add a legend explaining the colors and\nlabels to the DOT output.")
+	cmd.Flags().BoolVar(&importsFlags.cluster, clusterFlag, false, "Whether to box the nodes of the DOT output into clusters\nthat follow the packages' directories.")
+	cmd.Flags().StringVar(&importsFlags.colorBy, colorByFlag, "", fmt.Sprintf("Color the nodes of the DOT output on a heatmap by one of:\n%s.", strings.Join(tree.Measures, ", ")))
+	cmd.Flags().StringVar(&importsFlags.sizeBy, sizeByFlag, "", "Size the nodes of the DOT output by one of the same\nmeasures as --"+colorByFlag+".")
+	cmd.Flags().StringVar(&importsFlags.coverage, coverprofileFlag, "", "A coverage profile (from go test -coverprofile) to\nread the coverage measure from.")
+	cmd.Flags().StringArrayVar(&importsFlags.collapse, collapseFlag, []string{}, "Merge the packages under this prefix (e.g. pkg/tree/...)\ninto one node. Imports between merged nodes are labelled\nwith how many package imports they stand for.")
+	cmd.Flags().IntVar(&importsFlags.depth, collapseDepthFlag, 0, "Merge the packages inside the parent directory by\ntheir first N path elements. Prefixes named with\n--"+collapseFlag+" take precedence.")
+	cmd.Flags().BoolVar(&importsFlags.symbols, symbolsFlag, false, "[SLOW] Type-check the packages, and label each import\nwith the exported identifiers it uses. The JSON output\nlists them all, with how many times each is used.")
+	cmd.Flags().BoolVar(&importsFlags.detail, edgeDetailFlag, false, "[SLOW] Instead of a graph, list each import with the\nexported identifiers it uses and how many times.")
+	cmd.Flags().StringVar(&importsFlags.format, formatFlag, dotFormat, formatUsage())
+	cmd.Flags().StringVarP(&importsFlags.output, outputFlag, "o", "", fmt.Sprintf("Render the graph to this file instead of printing DOT. Its\nextension picks the format: .%s. Uses graphviz's\ndot command if it is installed; without it, only .%s works.", strings.Join(render.Formats, ", ."), render.SVG))
+	importsFlags.load.addFlags(cmd.Flags())
+	importsFlags.load.addRefFlag(cmd.Flags())
 
 	return cmd
 }
 
+// trim keeps the named packages and grows the tree around them, or failing
+// that, keeps the branches leading to the named packages. Either way, the rest
+// of the tree is pruned away. If no packages are named, the tree is left
+// whole.
+func trim(t *tree.Tree, keeps, branches []string, grow int) error {
+	for _, name := range keeps {
+		if err := t.Keep(name); err != nil {
+			return err
+		}
+	}
+
+	// honor either keeps or branch, not both
+	if len(keeps) > 0 {
+		t.Grow(grow)
+		t.Prune()
+		return nil
+	}
+	for _, branch := range branches {
+		if err := t.Branch(branch); err != nil {
+			return err
+		}
+	}
+	if len(branches) > 0 {
+		t.Prune()
+	}
+	return nil
+}
+
+// writeGraph prints the formatted graph, or renders it to the named output
+// file if there is one.
+func writeGraph(graph, format, output string) error {
+	if output == "" {
+		fmt.Println(graph)
+		return nil
+	}
+	if format != dotFormat {
+		return fmt.Errorf("--%s renders DOT, so it can't be used with --%s %s", outputFlag, formatFlag, format)
+	}
+	if err := render.File(graph, output); err != nil {
+		return err
+	}
+	logrus.Infof("Wrote %s", output)
+	return nil
+}
+
+// setMeasures sets up the tree to color and size its nodes by the measures the
+// flags name.
+func setMeasures(t *tree.Tree) error {
+	if err := t.SetColorBy(importsFlags.colorBy); err != nil {
+		return err
+	}
+	if err := t.SetSizeBy(importsFlags.sizeBy); err != nil {
+		return err
+	}
+
+	usesCoverage := importsFlags.colorBy == tree.Coverage || importsFlags.sizeBy == tree.Coverage
+	if importsFlags.coverage == "" {
+		if usesCoverage {
+			return fmt.Errorf("the %s measure needs a coverage profile; name one with --%s", tree.Coverage, coverprofileFlag)
+		}
+		return nil
+	}
+	f, err := os.Open(importsFlags.coverage)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	coverage, err := tree.ReadCoverProfile(f)
+	if err != nil {
+		return fmt.Errorf("%s: %v", importsFlags.coverage, err)
+	}
+	t.SetCoverage(coverage)
+	return nil
+}
+
 func validateImportsArgs(cmd *cobra.Command, args []string) error {
 	if len(args) < 2 {
 		return fmt.Errorf("must provide at lease two arguments, the parent directory and at least one package (to be the root of the graph)")
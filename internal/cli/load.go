@@ -0,0 +1,223 @@
+package cli
+
+import (
+	"fmt"
+	"go/build"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/spilliams/goraffe/internal/worktree"
+	"github.com/spilliams/goraffe/pkg/filter"
+	"github.com/spilliams/goraffe/pkg/tree"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/pflag"
+)
+
+// the names of the flags that control how packages are loaded
+const (
+	testsFlag     = "tests"
+	extsFlag      = "exts"
+	dirFlag       = "dir"
+	jobsFlag      = "jobs"
+	noCacheFlag   = "no-cache"
+	goosFlag      = "goos"
+	goarchFlag    = "goarch"
+	tagsFlag      = "tags"
+	platformsFlag = "platforms"
+	atRefFlag     = "at-ref"
+	includeFlag   = "include"
+	excludeFlag   = "exclude"
+)
+
+// loadOptions holds the flags that control how a command loads its tree.
+type loadOptions struct {
+	tests     bool
+	exts      bool
+	dir       string
+	jobs      int
+	noCache   bool
+	goos      string
+	goarch    string
+	tags      []string
+	platforms []string
+	atRef     string
+	includes  []string
+	excludes  []string
+	// inspect, if set, is run on each loaded tree with the directory the tree
+	// was loaded from, and the tree it returns is used instead. Commands that
+	// need more from the code than its imports (e.g. its types, or its line
+	// counts) set it themselves, and must read the files here: with --at-ref,
+	// they're only on disk while loading.
+	inspect func(t *tree.Tree, dir string, ctx *build.Context) (*tree.Tree, error)
+}
+
+func (o *loadOptions) addFlags(fs *pflag.FlagSet) {
+	o.addContextFlags(fs)
+	fs.BoolVar(&o.tests, testsFlag, false, "Whether to include imports from Go test files. Imports\nmade only by tests are drawn dashed, and external test\npackages (foo_test) get their own dashed nodes.")
+	fs.BoolVar(&o.exts, extsFlag, false, "[SLOW] Whether to include packages from outside the\nparent directory.")
+	fs.IntVar(&o.jobs, jobsFlag, 0, "How many packages to load at once. 0 means one per CPU.")
+	fs.BoolVar(&o.noCache, noCacheFlag, false, "Load every package afresh, without reading or writing\nthe package cache.")
+	fs.StringArrayVar(&o.includes, includeFlag, []string{}, "Only include packages matching this pattern. Patterns are\nglobs (**/mocks), go patterns (.../generated/...) or\n/regular expressions/, matched against both the import\npath and the path relative to the parent directory.")
+	fs.StringArrayVar(&o.excludes, excludeFlag, []string{}, "Leave out packages matching this pattern (see --"+includeFlag+").")
+	fs.StringSliceVar(&o.platforms, platformsFlag, []string{}, "Load the tree once for each of these os/arch pairs\n(e.g. linux/amd64,windows/amd64), and graph the union.\nEach import is annotated with the platforms it exists on.\nOverrides --"+goosFlag+" and --"+goarchFlag+".")
+}
+
+// addContextFlags adds the flags for where and for which platform packages are
+// loaded. Commands that load packages their own way only take these.
+func (o *loadOptions) addContextFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&o.dir, dirFlag, ".", "The directory to resolve packages from. If it is inside a\nGo module, packages are loaded in module mode.")
+	fs.StringVar(&o.goos, goosFlag, "", "The target operating system to load packages for.\nDefaults to the host's.")
+	fs.StringVar(&o.goarch, goarchFlag, "", "The target architecture to load packages for.\nDefaults to the host's.")
+	fs.StringSliceVar(&o.tags, tagsFlag, []string{}, "Build tags to consider satisfied while loading packages.")
+}
+
+// addRefFlag adds the flag for loading the code at a git revision. Commands
+// that compare revisions name their revisions their own way instead.
+func (o *loadOptions) addRefFlag(fs *pflag.FlagSet) {
+	fs.StringVar(&o.atRef, atRefFlag, "", "Load the code as it is at this git revision (e.g.\norigin/main), instead of as it is on disk. The revision\nis checked out into a temporary worktree of the\nrepository containing --"+dirFlag+", which must be inside a\nGo module.")
+}
+
+// load builds a tree under the given parent directory, starting from the given
+// roots.
+func (o *loadOptions) load(parentDirectory string, roots []string) (*tree.Tree, error) {
+	if o.atRef != "" {
+		wt, err := worktree.Add(o.dir, o.atRef)
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			if err := wt.Remove(); err != nil {
+				logrus.Warnf("could not remove worktree: %v", err)
+			}
+		}()
+		// outside a module, packages are resolved from GOPATH, not from the
+		// worktree
+		gomod, err := tree.ModuleFile(wt.Dir)
+		if err != nil {
+			return nil, err
+		}
+		if gomod == "" {
+			return nil, fmt.Errorf("--%s only works inside a Go module, and %s isn't in one at %s", atRefFlag, o.dir, o.atRef)
+		}
+		logrus.Infof("Loading %s", o.atRef)
+
+		atRef := *o
+		atRef.atRef = ""
+		atRef.dir = wt.Dir
+		// the worktree's path is new every time, so its packages would never
+		// be found in the cache again
+		atRef.noCache = true
+		return atRef.load(parentDirectory, roots)
+	}
+
+	if len(o.platforms) == 0 {
+		return o.loadFor(parentDirectory, roots, o.buildContext(o.goos, o.goarch))
+	}
+
+	trees := make(map[string]*tree.Tree)
+	for _, platform := range o.platforms {
+		goos, goarch, ok := strings.Cut(platform, "/")
+		if !ok || goos == "" || goarch == "" {
+			return nil, fmt.Errorf("platform %q should look like os/arch", platform)
+		}
+		logrus.Infof("Loading for %s", platform)
+		t, err := o.loadFor(parentDirectory, roots, o.buildContext(goos, goarch))
+		if err != nil {
+			return nil, err
+		}
+		trees[platform] = t
+	}
+	return tree.Union(trees), nil
+}
+
+func (o *loadOptions) loadFor(parentDirectory string, roots []string, ctx *build.Context) (*tree.Tree, error) {
+	loader, err := newLoader(o.dir, parentDirectory, ctx, o.noCache)
+	if err != nil {
+		return nil, err
+	}
+	t := tree.NewTree(parentDirectory, loader)
+
+	f, err := filter.New(o.includes, o.excludes)
+	if err != nil {
+		return nil, err
+	}
+	if !f.Empty() {
+		prefix := path.Clean(parentDirectory) + "/"
+		t.SetFilter(func(importPath string) bool {
+			return f.Include(importPath, strings.TrimPrefix(importPath, prefix))
+		})
+	}
+
+	t.SetIncludeTests(o.tests)
+	t.SetIncludeExts(o.exts)
+	t.SetJobs(o.jobs)
+
+	for _, pkg := range roots {
+		if _, err := t.AddRecursive(pkg); err != nil {
+			return nil, err
+		}
+	}
+	if o.inspect != nil {
+		return o.inspect(t, o.dir, ctx)
+	}
+	return t, nil
+}
+
+// buildContext returns the default build context, adjusted for the given
+// platform (if any) and the receiver's tags.
+func (o *loadOptions) buildContext(goos, goarch string) *build.Context {
+	ctx := build.Default
+	if goos != "" {
+		ctx.GOOS = goos
+	}
+	if goarch != "" {
+		ctx.GOARCH = goarch
+	}
+	ctx.BuildTags = append([]string{}, o.tags...)
+	return &ctx
+}
+
+// newLoader returns a module loader if the given directory is inside a Go
+// module, or a GOPATH loader otherwise. Unless told not to, it wraps that
+// loader in an on-disk cache.
+func newLoader(dir, parentDirectory string, ctx *build.Context, noCache bool) (tree.Loader, error) {
+	gomod, err := tree.ModuleFile(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var loader tree.Loader
+	var namespace string
+	if gomod == "" {
+		loader = tree.NewGOPATHLoader(ctx)
+		namespace = fmt.Sprintf("gopath %s", ctx.GOPATH)
+	} else {
+		logrus.Debugf("using module %s", gomod)
+		loader = tree.NewModuleLoader(dir, ctx, path.Clean(parentDirectory)+"/...")
+		// the module's requirements affect where its imports resolve to
+		namespace = fmt.Sprintf("module %s %s %s", gomod, stamp(gomod), stamp(filepath.Join(filepath.Dir(gomod), "go.sum")))
+	}
+	namespace += fmt.Sprintf(" %s/%s %s", ctx.GOOS, ctx.GOARCH, strings.Join(ctx.BuildTags, ","))
+
+	if noCache {
+		return loader, nil
+	}
+	cacheDir, err := tree.DefaultCacheDir()
+	if err != nil {
+		logrus.Warnf("not caching packages: %v", err)
+		return loader, nil
+	}
+	return tree.NewCachedLoader(loader, cacheDir, namespace), nil
+}
+
+// stamp returns a string that changes whenever the named file does.
+func stamp(name string) string {
+	info, err := os.Stat(name)
+	if err != nil {
+		return "-"
+	}
+	return fmt.Sprintf("%d:%d", info.Size(), info.ModTime().UnixNano())
+}
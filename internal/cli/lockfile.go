@@ -0,0 +1,125 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spilliams/goraffe/pkg/lockfile"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// the names of the flags
+const (
+	lockfileFlag = "lockfile"
+	strictFlag   = "strict"
+)
+
+const defaultLockfile = "goraffe.lock"
+
+var freezeFlags struct {
+	lockfile string
+	load     loadOptions
+}
+
+var verifyFlags struct {
+	lockfile string
+	strict   bool
+	load     loadOptions
+}
+
+func newFreezeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "freeze <parent directory> <root packages>",
+		Args:    validateImportsArgs,
+		Example: "goraffe freeze github.com/spilliams/goraffe cmd/goraffe",
+		Short:   "Record the current imports in a lockfile",
+		Long: `Record the current imports in a lockfile.
+
+This command loads a tree the same way ` + "`imports`" + ` does, and writes every
+import in it to the lockfile, one per line. Commit the lockfile, and use
+` + "`goraffe verify`" + ` (with the same arguments) to stop new imports creeping in.
+Run freeze again whenever imports are removed, to tighten the lockfile.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			importTree, err := freezeFlags.load.load(args[0], args[1:])
+			if err != nil {
+				return err
+			}
+
+			l := lockfile.FromTree(importTree)
+			if err := l.WriteFile(freezeFlags.lockfile); err != nil {
+				return err
+			}
+			logrus.Infof("Wrote %d imports to %s", len(l.Imports), freezeFlags.lockfile)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&freezeFlags.lockfile, lockfileFlag, defaultLockfile, "The lockfile to write.")
+	freezeFlags.load.addFlags(cmd.Flags())
+	freezeFlags.load.addRefFlag(cmd.Flags())
+
+	return cmd
+}
+
+func newVerifyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "verify <parent directory> <root packages>",
+		Args:    validateImportsArgs,
+		Example: "goraffe verify github.com/spilliams/goraffe cmd/goraffe",
+		Short:   "Check that no imports were added since the lockfile was written",
+		Long: `Check that no imports were added since the lockfile was written.
+
+This command loads a tree the same way ` + "`imports`" + ` does, and compares its
+imports with the lockfile's (see ` + "`goraffe freeze`" + `). It lists any import that
+isn't in the lockfile, and exits non-zero if there are any.
+
+It also lists stale lockfile entries: imports that have since gone away. These
+don't fail the check unless you pass --strict, but running ` + "`goraffe freeze`" + `
+again will remove them, so that they can't come back unnoticed.`,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			locked, err := lockfile.ReadFile(verifyFlags.lockfile)
+			if err != nil {
+				return err
+			}
+
+			importTree, err := verifyFlags.load.load(args[0], args[1:])
+			if err != nil {
+				return err
+			}
+			if locked.Parent != "" && locked.Parent != importTree.ParentDirectory() {
+				logrus.Warnf("%s was written for %s, not %s", verifyFlags.lockfile, locked.Parent, importTree.ParentDirectory())
+			}
+
+			added, stale := locked.Compare(lockfile.FromTree(importTree))
+			for _, i := range added {
+				fmt.Printf("new import: %s\n", i)
+			}
+			for _, i := range stale {
+				fmt.Printf("stale lockfile entry: %s\n", i)
+			}
+
+			if len(added) > 0 {
+				logrus.Errorf("%d imports are not in %s", len(added), verifyFlags.lockfile)
+				return exitError{code: 1}
+			}
+			if len(stale) > 0 {
+				if verifyFlags.strict {
+					logrus.Errorf("%d entries in %s are stale; run `goraffe freeze` to remove them", len(stale), verifyFlags.lockfile)
+					return exitError{code: 1}
+				}
+				logrus.Warnf("%d entries in %s are stale; run `goraffe freeze` to remove them", len(stale), verifyFlags.lockfile)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&verifyFlags.lockfile, lockfileFlag, defaultLockfile, "The lockfile to check against.")
+	cmd.Flags().BoolVar(&verifyFlags.strict, strictFlag, false, "Fail on stale lockfile entries too.")
+	verifyFlags.load.addFlags(cmd.Flags())
+	verifyFlags.load.addRefFlag(cmd.Flags())
+
+	return cmd
+}
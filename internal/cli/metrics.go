@@ -0,0 +1,121 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/build"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spilliams/goraffe/pkg/tree"
+
+	"github.com/spf13/cobra"
+)
+
+// the output formats metrics can be written in
+const tableFormat = "table"
+
+var metricsFormats = []string{tableFormat, jsonFormat}
+
+// the columns metrics can be sorted by
+var metricsSorts = map[string]func(m tree.Metrics) float64{
+	"ca": func(m tree.Metrics) float64 { return float64(m.Afferent) },
+	"ce": func(m tree.Metrics) float64 { return float64(m.Efferent) },
+	"i":  func(m tree.Metrics) float64 { return m.Instability },
+	"a":  func(m tree.Metrics) float64 { return m.Abstractness },
+	"d":  func(m tree.Metrics) float64 { return m.Distance },
+}
+
+// metricsSchemaVersion is the version of the JSON document the metrics command
+// writes. It changes whenever a field is removed or changes meaning.
+const metricsSchemaVersion = 1
+
+const sortFlag = "sort"
+
+var metricsFlags struct {
+	format string
+	sort   string
+	load   loadOptions
+}
+
+func newMetricsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "metrics <parent directory> <root packages>",
+		Args:    validateImportsArgs,
+		Example: "goraffe metrics github.com/spilliams/goraffe cmd/goraffe --sort d",
+		Short:   "Measure how stable and abstract each package is",
+		Long: `Measure how stable and abstract each package is.
+
+This command loads a tree the same way ` + "`imports`" + ` does, and prints Robert C.
+Martin's package metrics for each of its packages:
+
+  Ca  afferent coupling: how many of the tree's packages import it
+  Ce  efferent coupling: how many packages it imports
+  I   instability, Ce / (Ca + Ce)
+  A   abstractness: how many of its named types are interfaces, as a fraction
+  D   distance from the main sequence, |A + I - 1|
+
+Packages far from the main sequence are either concrete and depended on by many
+(hard to change), or abstract and depended on by few (useless). Only production
+imports are counted.
+
+The table is sorted by package name, or with --sort by one of the columns
+(ca, ce, i, a, d), highest first. With --format json it writes a JSON document
+instead, with a "schemaVersion" and a list of "packages".`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			sortBy, ok := metricsSorts[metricsFlags.sort]
+			if !ok && metricsFlags.sort != "name" {
+				return fmt.Errorf("unknown sort %q, must be one of: name, ca, ce, i, a, d", metricsFlags.sort)
+			}
+
+			metricsFlags.load.inspect = func(t *tree.Tree, dir string, ctx *build.Context) (*tree.Tree, error) {
+				t.CountSources()
+				return t, nil
+			}
+			importTree, err := metricsFlags.load.load(args[0], args[1:])
+			if err != nil {
+				return err
+			}
+
+			metrics := importTree.Metrics()
+			if sortBy != nil {
+				sort.SliceStable(metrics, func(i, j int) bool {
+					return sortBy(metrics[i]) > sortBy(metrics[j])
+				})
+			}
+
+			switch metricsFlags.format {
+			case tableFormat:
+				return writeMetricsTable(metrics)
+			case jsonFormat:
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				return enc.Encode(struct {
+					SchemaVersion   int            `json:"schemaVersion"`
+					ParentDirectory string         `json:"parentDirectory"`
+					Packages        []tree.Metrics `json:"packages"`
+				}{metricsSchemaVersion, importTree.ParentDirectory(), metrics})
+			}
+			return fmt.Errorf("unknown format %q, must be one of: %s", metricsFlags.format, strings.Join(metricsFormats, ", "))
+		},
+	}
+
+	cmd.Flags().StringVar(&metricsFlags.format, formatFlag, tableFormat, fmt.Sprintf("The output format, one of: %s.", strings.Join(metricsFormats, ", ")))
+	cmd.Flags().StringVar(&metricsFlags.sort, sortFlag, "name", "The column to sort by: name, ca, ce, i, a or d.")
+	metricsFlags.load.addFlags(cmd.Flags())
+	metricsFlags.load.addRefFlag(cmd.Flags())
+
+	return cmd
+}
+
+func writeMetricsTable(metrics []tree.Metrics) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "package\tCa\tCe\tI\tA\tD\t")
+	for _, m := range metrics {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%.2f\t%.2f\t%.2f\t\n",
+			m.DisplayName, m.Afferent, m.Efferent, m.Instability, m.Abstractness, m.Distance)
+	}
+	return w.Flush()
+}
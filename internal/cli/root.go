@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
@@ -18,10 +19,24 @@ var rootCmd = &cobra.Command{
 	Version: version.Info(),
 }
 
+// exitError is returned by commands that have already reported why they
+// failed, and just need goraffe to exit with the given code.
+type exitError struct {
+	code int
+}
+
+func (e exitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.code)
+}
+
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
+		var exit exitError
+		if errors.As(err, &exit) {
+			os.Exit(exit.code)
+		}
 		fmt.Println(err)
 		os.Exit(1)
 	}
@@ -32,8 +47,18 @@ func init() {
 
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "verbose output")
 
+	rootCmd.AddCommand(newCacheCmd())
+	rootCmd.AddCommand(newCalltreeCmd())
+	rootCmd.AddCommand(newCheckCmd())
+	rootCmd.AddCommand(newCyclesCmd())
+	rootCmd.AddCommand(newDiffCmd())
+	rootCmd.AddCommand(newFreezeCmd())
 	rootCmd.AddCommand(newImportsCmd())
+	rootCmd.AddCommand(newMetricsCmd())
+	rootCmd.AddCommand(newTypesCmd())
+	rootCmd.AddCommand(newVerifyCmd())
 	rootCmd.AddCommand(newVersionCmd())
+	rootCmd.AddCommand(newWhyCmd())
 }
 
 func initLogger() {
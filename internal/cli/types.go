@@ -0,0 +1,97 @@
+package cli
+
+import (
+	"fmt"
+	"go/build"
+
+	"github.com/spilliams/goraffe/pkg/tree"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// the names of the flags
+const (
+	exportedFlag = "exported"
+)
+
+var typesFlags struct {
+	grow     int
+	keeps    []string
+	branches []string
+	exported bool
+	format   string
+	legend   bool
+	cluster  bool
+	output   string
+	load     loadOptions
+}
+
+func newTypesCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "types <parent directory> <root packages>",
+		Args:    validateImportsArgs,
+		Example: "goraffe types github.com/spilliams/goraffe pkg/tree --exported",
+		Short:   "Visualize how types depend on each other",
+		Long: `Visualize how types depend on each other.
+
+This command loads a tree of packages the same way ` + "`imports`" + ` does, then
+type-checks them and graphs their named types instead. Each type points to the
+types it refers to: in its fields and embedded types, in its underlying type,
+and in the parameters and results of its methods. Each edge is labelled with
+how, like "field deps" or "method Edge result". The types declared in the root
+packages are the roots, and the tree holds them and every type they lead to.
+
+Types are named like "pkg/tree.Tree". --keep, --grow and --branch take those
+names, and trim the tree the same way they do for ` + "`imports`" + `. With --exported,
+only exported types are included.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkLegend(typesFlags.legend, typesFlags.format); err != nil {
+				return err
+			}
+			if len(typesFlags.load.platforms) > 0 {
+				return fmt.Errorf("types can't be graphed for several platforms at once; use --%s and --%s instead of --%s", goosFlag, goarchFlag, platformsFlag)
+			}
+			typesFlags.load.inspect = func(t *tree.Tree, dir string, ctx *build.Context) (*tree.Tree, error) {
+				return t.Types(dir, ctx, typesFlags.exported)
+			}
+			typeTree, err := typesFlags.load.load(args[0], args[1:])
+			if err != nil {
+				return err
+			}
+
+			if err := trim(typeTree, typesFlags.keeps, typesFlags.branches, typesFlags.grow); err != nil {
+				return err
+			}
+
+			logrus.Debug(typeTree)
+
+			typeTree.SetLegend(typesFlags.legend)
+			typeTree.SetCluster(typesFlags.cluster)
+			graph, err := formatTree(typeTree, typesFlags.format)
+			if err != nil {
+				return err
+			}
+			if err := writeGraph(graph, typesFlags.format, typesFlags.output); err != nil {
+				return err
+			}
+
+			logrus.Info(typeTree.Stats())
+
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVar(&typesFlags.grow, growFlag, 1, "How far to \"grow\" the tree away from any kept\ntypes. Use with --"+keepFlag+".")
+	cmd.Flags().StringArrayVar(&typesFlags.keeps, keepFlag, []string{}, "Designate some types to \"keep\", and prune away\nthe rest.")
+	cmd.Flags().StringArrayVar(&typesFlags.branches, branchFlag, []string{}, "Designate a type to branch to--the tree will include the\nroots and this branch, and just the types in between.")
+	cmd.Flags().BoolVar(&typesFlags.exported, exportedFlag, false, "Only include exported types.")
+	cmd.Flags().BoolVar(&typesFlags.legend, legendFlag, false, "Whether to add a legend explaining the colors and\nlabels to the DOT output.")
+	cmd.Flags().BoolVar(&typesFlags.cluster, clusterFlag, false, "Whether to box the types of the DOT output into clusters\nthat follow their packages' directories.")
+	cmd.Flags().StringVar(&typesFlags.format, formatFlag, dotFormat, formatUsage())
+	cmd.Flags().StringVarP(&typesFlags.output, outputFlag, "o", "", "Render the graph to this file instead of printing DOT\n(see --"+outputFlag+" on imports).")
+	typesFlags.load.addFlags(cmd.Flags())
+	typesFlags.load.addRefFlag(cmd.Flags())
+
+	return cmd
+}
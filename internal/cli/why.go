@@ -0,0 +1,75 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spilliams/goraffe/pkg/tree"
+
+	"github.com/spf13/cobra"
+)
+
+// the names of the flags
+const (
+	allPathsFlag = "all-paths"
+	limitFlag    = "limit"
+)
+
+var whyFlags struct {
+	allPaths bool
+	limit    int
+	load     loadOptions
+}
+
+func newWhyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "why <parent directory> <from package> <to package>",
+		Args:    cobra.ExactArgs(3),
+		Example: "goraffe why github.com/spilliams/goraffe cmd/goraffe pkg/tree",
+		Short:   "Explain how one package comes to import another",
+		Long: `Explain how one package comes to import another.
+
+This command loads the tree of the "from" package (the same way ` + "`imports`" + `
+does), then prints the shortest chain of imports that leads from it to the "to"
+package, one package per line. With --all-paths it prints several chains,
+shortest first, separated by blank lines.
+
+Both packages can be named with or without the parent directory prefix.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			importTree, err := whyFlags.load.load(args[0], args[1:2])
+			if err != nil {
+				return err
+			}
+
+			var paths [][]string
+			if whyFlags.allPaths {
+				paths, err = importTree.AllPaths(args[1], args[2], whyFlags.limit)
+			} else {
+				var p []string
+				p, err = importTree.ShortestPath(args[1], args[2])
+				paths = [][]string{p}
+			}
+			if err != nil {
+				return err
+			}
+
+			for i, p := range paths {
+				if i > 0 {
+					fmt.Println()
+				}
+				fmt.Printf("# %s -> %s\n", importTree.DisplayName(args[1]), importTree.DisplayName(args[2]))
+				for _, name := range p {
+					fmt.Println(importTree.DisplayName(name))
+				}
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&whyFlags.allPaths, allPathsFlag, false, "Print every chain of imports, not just the shortest.")
+	cmd.Flags().IntVar(&whyFlags.limit, limitFlag, 10, fmt.Sprintf("The most chains to print with --%s. 0 means as many\nas allowed, %d.", allPathsFlag, tree.MaxPaths))
+	whyFlags.load.addFlags(cmd.Flags())
+	whyFlags.load.addRefFlag(cmd.Flags())
+
+	return cmd
+}
@@ -0,0 +1,82 @@
+// Package worktree checks git revisions out into temporary worktrees, so that
+// goraffe can load the code as it was at that revision.
+package worktree
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Worktree is a temporary, detached checkout of one revision of a repository.
+type Worktree struct {
+	// Dir is the directory in the worktree that corresponds to the directory
+	// the worktree was made from.
+	Dir string
+
+	repo string
+	root string
+}
+
+// Add checks the given revision (any ref, hash or expression git understands)
+// of the repository containing dir out into a new temporary worktree. Call
+// Remove when done with it.
+func Add(dir, rev string) (*Worktree, error) {
+	repo, err := git(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	prefix, err := git(dir, "rev-parse", "--show-prefix")
+	if err != nil {
+		return nil, err
+	}
+	hash, err := git(dir, "rev-parse", "--verify", rev+"^{commit}")
+	if err != nil {
+		return nil, fmt.Errorf("unknown revision %q: %v", rev, err)
+	}
+
+	root, err := os.MkdirTemp("", "goraffe-")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := git(repo, "worktree", "add", "--detach", root, hash); err != nil {
+		os.RemoveAll(root)
+		return nil, err
+	}
+	logrus.Debugf("checked %s (%s) out into %s", rev, hash, root)
+
+	return &Worktree{
+		Dir:  filepath.Join(root, prefix),
+		repo: repo,
+		root: root,
+	}, nil
+}
+
+// Remove deletes the receiver's checkout, and tells the repository it's gone.
+func (w *Worktree) Remove() error {
+	_, err := git(w.repo, "worktree", "remove", "--force", w.root)
+	if rmErr := os.RemoveAll(w.root); err == nil {
+		err = rmErr
+	}
+	return err
+}
+
+// git runs git in the given directory, and returns its trimmed output.
+func git(dir string, args ...string) (string, error) {
+	logrus.Debugf("git %s (in %s)", strings.Join(args, " "), dir)
+
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %v: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
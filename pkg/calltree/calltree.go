@@ -0,0 +1,402 @@
+// Package calltree traces the callers of a function back to the functions
+// nothing calls (main, init, exported entry points and tests), and builds a
+// tree of them that can be kept, grown, pruned and drawn like an import tree.
+package calltree
+
+import (
+	"fmt"
+	"go/build"
+	"go/types"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spilliams/goraffe/pkg/tree"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/callgraph/cha"
+	"golang.org/x/tools/go/callgraph/vta"
+	"golang.org/x/tools/go/packages"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/ssautil"
+)
+
+// the call graph algorithms
+const (
+	// CHA (class hierarchy analysis) assumes a dynamic call may reach any
+	// function with the right signature. It is fast, but over-approximates.
+	CHA = "cha"
+	// VTA (variable type analysis) follows where function values and
+	// interface values flow, so it finds fewer spurious callers.
+	VTA = "vta"
+)
+
+// Algorithms lists the call graph algorithms Trace knows.
+var Algorithms = []string{CHA, VTA}
+
+// TestRoot is the name of the synthetic root that callers in test files lead
+// to. They are not traced any further.
+const TestRoot = "test"
+
+// Options controls how Trace loads and analyzes the program.
+type Options struct {
+	// Dir is the directory to run the go command in.
+	Dir string
+	// Context is the platform and build tags to load the packages for. If it
+	// is nil, the go command's defaults are used.
+	Context *build.Context
+	// Tests is whether to load test files, so that calls from tests are found.
+	Tests bool
+	// Algorithm is the call graph algorithm to use, one of Algorithms.
+	Algorithm string
+}
+
+// Graph is the set of functions that lead, through calls, to one target
+// function. Functions are named by import path and name, like
+// "github.com/spilliams/goraffe/pkg/tree.(*Tree).Grow". Closures are named
+// after the function they are declared in, like "pkg/cli.newRootCmd$1".
+type Graph struct {
+	parentDirectory string
+	target          string
+	// calls holds, by caller and then callee, the files the calls are in
+	calls   map[string]map[string][]string
+	callers map[string]bool
+	dirs    map[string]string
+}
+
+// Trace loads the packages under the parent directory, builds their call graph,
+// and follows the callers of the named function back to the functions nothing
+// calls, keeping those inside the parent directory. The package may be named with or without the parent
+// directory prefix. The function may be a function's name ("NewTree") or a
+// method's ("Tree.Grow" or "(*Tree).Grow").
+func Trace(parentDirectory, pkg, function string, opts Options) (*Graph, error) {
+	prog, err := load(parentDirectory, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	targets, err := lookup(prog, parentDirectory, pkg, function)
+	if err != nil {
+		return nil, err
+	}
+
+	var cg *callgraph.Graph
+	switch opts.Algorithm {
+	case CHA:
+		cg = cha.CallGraph(prog)
+	case VTA, "":
+		cg = vta.CallGraph(ssautil.AllFunctions(prog), cha.CallGraph(prog))
+	default:
+		return nil, fmt.Errorf("unknown algorithm %q, must be one of: %s", opts.Algorithm, strings.Join(Algorithms, ", "))
+	}
+
+	g := &Graph{
+		parentDirectory: parentDirectory,
+		target:          funcName(targets[0]),
+		calls:           make(map[string]map[string][]string),
+		callers:         make(map[string]bool),
+		dirs:            make(map[string]string),
+	}
+	g.trace(prog, cg, targets)
+	return g, nil
+}
+
+// load loads and type-checks the packages under the parent directory, and
+// builds the SSA form of them and everything they depend on, so that calls
+// can be followed through their dependencies.
+func load(parentDirectory string, opts Options) (*ssa.Program, error) {
+	cfg := &packages.Config{
+		Mode:  packages.LoadAllSyntax,
+		Dir:   opts.Dir,
+		Tests: opts.Tests,
+		Env:   os.Environ(),
+		Logf:  logrus.Debugf,
+	}
+	if gomod, err := tree.ModuleFile(opts.Dir); err == nil && gomod == "" {
+		cfg.Env = append(cfg.Env, "GO111MODULE=off")
+	}
+	if ctx := opts.Context; ctx != nil {
+		cfg.Env = append(cfg.Env, "GOOS="+ctx.GOOS, "GOARCH="+ctx.GOARCH)
+		if len(ctx.BuildTags) > 0 {
+			cfg.BuildFlags = []string{"-tags", strings.Join(ctx.BuildTags, ",")}
+		}
+	}
+
+	logrus.Infof("Loading %s/...", parentDirectory)
+	pkgs, err := packages.Load(cfg, parentDirectory+"/...")
+	if err != nil {
+		return nil, fmt.Errorf("could not load packages: %v", err)
+	}
+	if len(pkgs) == 0 {
+		return nil, fmt.Errorf("no packages found under %s", parentDirectory)
+	}
+	packages.Visit(pkgs, nil, func(p *packages.Package) {
+		for _, err := range p.Errors {
+			logrus.Warnf("%s: %v", p.ID, err)
+		}
+	})
+
+	logrus.Info("Building the call graph")
+	prog, _ := ssautil.AllPackages(pkgs, ssa.InstantiateGenerics)
+	prog.Build()
+	return prog, nil
+}
+
+// lookup returns the SSA functions for the named function. A package loaded
+// with tests has a variant with them and one without, so there may be several.
+func lookup(prog *ssa.Program, parentDirectory, pkg, function string) ([]*ssa.Function, error) {
+	paths := []string{filepath.ToSlash(filepath.Join(parentDirectory, strings.TrimPrefix(pkg, parentDirectory))), pkg}
+
+	typeName, method, isMethod := strings.Cut(function, ".")
+	typeName = strings.Trim(typeName, "(*)")
+
+	found := false
+	targets := []*ssa.Function{}
+	for _, p := range prog.AllPackages() {
+		if p.Pkg.Path() != paths[0] && p.Pkg.Path() != paths[1] {
+			continue
+		}
+		found = true
+		if !isMethod {
+			if fn := p.Func(function); fn != nil {
+				targets = append(targets, fn)
+			}
+			continue
+		}
+		t := p.Type(typeName)
+		if t == nil {
+			continue
+		}
+		// a pointer finds methods with either kind of receiver, but an
+		// interface's methods are only found on the interface itself
+		recv := t.Type()
+		if !types.IsInterface(recv) {
+			recv = types.NewPointer(recv)
+		}
+		obj, _, _ := types.LookupFieldOrMethod(recv, true, p.Pkg, method)
+		f, ok := obj.(*types.Func)
+		if !ok {
+			continue
+		}
+		if types.IsInterface(t.Type()) {
+			return nil, fmt.Errorf("%s.%s is an interface method; name one of its implementations instead", pkg, function)
+		}
+		if fn := prog.FuncValue(f); fn != nil {
+			targets = append(targets, fn)
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("package %s not found", pkg)
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("function %s not found in package %s", function, pkg)
+	}
+	return targets, nil
+}
+
+// trace follows the callers of the targets back through the call graph, until
+// it reaches functions that nothing calls. Callers outside the parent directory
+// aren't recorded, but are followed: a function inside that calls one outside,
+// which leads to a call back inside, is recorded as making that call itself.
+func (g *Graph) trace(prog *ssa.Program, cg *callgraph.Graph, targets []*ssa.Function) {
+	type visit struct {
+		fn *ssa.Function
+		// the name the calls to fn are recorded against. Wrappers, thunks and
+		// functions outside the parent directory stand for the function inside
+		// that they lead to, so their callers are recorded as calling that
+		// instead.
+		callee string
+	}
+	seen := make(map[visit]bool)
+	queue := []visit{}
+	for _, fn := range targets {
+		queue = append(queue, visit{fn, funcName(fn)})
+	}
+	for len(queue) > 0 {
+		v := queue[0]
+		queue = queue[1:]
+		if seen[v] {
+			continue
+		}
+		seen[v] = true
+
+		node := cg.Nodes[v.fn]
+		if node == nil {
+			continue
+		}
+		for _, in := range node.In {
+			caller := in.Caller.Func
+			if caller.Synthetic != "" && caller.Pkg != nil && caller != caller.Pkg.Func("init") {
+				queue = append(queue, visit{caller, v.callee})
+				continue
+			}
+
+			file := ""
+			if in.Site != nil {
+				file = prog.Fset.Position(in.Site.Pos()).Filename
+			}
+			if strings.HasSuffix(file, "_test.go") {
+				g.addCall(TestRoot, v.callee, file)
+				continue
+			}
+			if caller.Pkg == nil || !g.inside(caller.Pkg.Pkg.Path()) {
+				// packages outside are initialized before the ones inside
+				// that import them, so their initializers can't lead back
+				// inside; with CHA they seem to, through the runtime
+				if caller.Pkg == nil || caller != caller.Pkg.Func("init") {
+					queue = append(queue, visit{caller, v.callee})
+				}
+				continue
+			}
+			name := funcName(caller)
+			if name == v.callee {
+				continue
+			}
+			g.addCall(name, v.callee, file)
+			if _, ok := g.dirs[name]; !ok && file != "" {
+				g.dirs[name] = filepath.Dir(file)
+			}
+			queue = append(queue, visit{caller, name})
+		}
+	}
+}
+
+// inside returns whether the import path is in the receiver's parent
+// directory.
+func (g *Graph) inside(importPath string) bool {
+	if strings.HasSuffix(importPath, ".test") {
+		// the generated main package that runs the tests
+		return false
+	}
+	return importPath == g.parentDirectory || strings.HasPrefix(importPath, g.parentDirectory+"/")
+}
+
+// addCall records a call from one function to another, made in the given file.
+func (g *Graph) addCall(caller, callee, file string) {
+	if g.calls[caller] == nil {
+		g.calls[caller] = make(map[string][]string)
+	}
+	files := g.calls[caller][callee]
+	if file != "" && !contains(files, filepath.Base(file)) {
+		files = append(files, filepath.Base(file))
+		sort.Strings(files)
+	}
+	g.calls[caller][callee] = files
+	g.callers[callee] = true
+}
+
+// funcName returns the name the graph knows a function by.
+func funcName(fn *ssa.Function) string {
+	if origin := fn.Origin(); origin != nil {
+		fn = origin
+	}
+	// closures are named after the function they're declared in
+	outer := fn
+	for outer.Parent() != nil {
+		outer = outer.Parent()
+	}
+	if outer.Pkg == nil {
+		return fn.String()
+	}
+
+	name := outer.Name()
+	if recv := outer.Signature.Recv(); recv != nil {
+		name = fmt.Sprintf("(%s).%s", types.TypeString(recv.Type(), func(*types.Package) string { return "" }), name)
+	}
+	return outer.Pkg.Pkg.Path() + "." + name + strings.TrimPrefix(fn.Name(), outer.Name())
+}
+
+// Target returns the name of the function the receiver traces.
+func (g *Graph) Target() string {
+	return g.target
+}
+
+// Roots returns the names of the functions that call towards the target but
+// aren't called by anything inside the parent directory, even through code
+// outside it (including TestRoot, if tests call towards the target), sorted.
+func (g *Graph) Roots() []string {
+	roots := []string{}
+	for caller := range g.calls {
+		if !g.callers[caller] {
+			roots = append(roots, caller)
+		}
+	}
+	sort.Strings(roots)
+	return roots
+}
+
+// Tree returns a tree of the calls leading from the given roots to the target.
+// Each "package" of the tree is a function, and each "import" is a call. Calls
+// from tests are drawn like test imports.
+func (g *Graph) Tree(roots []string) (*tree.Tree, error) {
+	loader := tree.NewFixtureLoader()
+	names := map[string]bool{g.target: true}
+	for caller := range g.calls {
+		names[caller] = true
+	}
+	for name := range names {
+		loader.Add(g.pkg(name))
+	}
+
+	t := tree.NewTree(g.parentDirectory, loader)
+	// the functions outside the parent directory are the ones it calls
+	t.SetIncludeExts(true)
+	t.SetIncludeTests(true)
+	for _, root := range roots {
+		if !names[root] {
+			return nil, fmt.Errorf("%s does not call %s", root, g.target)
+		}
+		if _, err := t.AddRecursive(root); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// pkg returns a package standing for the named function, "importing" the
+// functions it calls.
+func (g *Graph) pkg(name string) *tree.Package {
+	callees := make([]string, 0, len(g.calls[name]))
+	for callee := range g.calls[name] {
+		callees = append(callees, callee)
+	}
+	sort.Strings(callees)
+
+	files := []string{}
+	specs := []tree.ImportSpec{}
+	for _, callee := range callees {
+		for _, file := range g.calls[name][callee] {
+			if !contains(files, file) {
+				files = append(files, file)
+			}
+			specs = append(specs, tree.ImportSpec{File: file, Path: callee})
+		}
+	}
+	sort.Strings(files)
+
+	pkg := &tree.Package{
+		ImportPath:  name,
+		Dir:         g.dirs[name],
+		Name:        name[strings.LastIndex(name, "/")+1:],
+		ImportSpecs: specs,
+	}
+	if name == TestRoot {
+		pkg.TestGoFiles = files
+		pkg.TestImports = callees
+	} else {
+		pkg.GoFiles = files
+		pkg.Imports = callees
+	}
+	return pkg
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
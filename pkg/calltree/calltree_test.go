@@ -0,0 +1,167 @@
+package calltree
+
+import (
+	"reflect"
+	"testing"
+)
+
+// the testdata module's parent directory. Its packages call into
+// ex.com/calls/runner, which is outside it.
+const parent = "ex.com/calls/app"
+
+const (
+	app = parent + "."
+	lib = parent + "/lib."
+)
+
+// graphs holds the graphs traced so far, by algorithm and function, since
+// loading the testdata module is slow.
+var graphs = map[[2]string]*Graph{}
+
+func trace(t *testing.T, algorithm, function string) *Graph {
+	t.Helper()
+	key := [2]string{algorithm, function}
+	if g, ok := graphs[key]; ok {
+		return g
+	}
+	g, err := Trace(parent, "lib", function, Options{Dir: "testdata/calls", Tests: true, Algorithm: algorithm})
+	if err != nil {
+		t.Fatal(err)
+	}
+	graphs[key] = g
+	return g
+}
+
+func TestTrace(t *testing.T) {
+	tests := []struct {
+		name       string
+		algorithm  string
+		function   string
+		wantTarget string
+		wantRoots  []string
+		wantCalls  map[string]map[string][]string
+	}{
+		{
+			// main calls Target directly, through Square.Area behind an
+			// interface, and through a closure that runner calls; and so does
+			// a test
+			name:       "calls",
+			algorithm:  VTA,
+			function:   "Target",
+			wantTarget: lib + "Target",
+			wantRoots:  []string{app + "main", TestRoot},
+			wantCalls: map[string]map[string][]string{
+				app + "main": {
+					app + "describe": {"main.go"},
+					app + "direct":   {"main.go"},
+					app + "main$1":   {"main.go"},
+				},
+				app + "describe":      {lib + "(Square).Area": {"main.go"}},
+				app + "direct":        {lib + "Target": {"main.go"}},
+				app + "main$1":        {lib + "Target": {"main.go"}},
+				lib + "(Square).Area": {lib + "Target": {"lib.go"}},
+				TestRoot:              {lib + "Target": {"lib_test.go"}},
+			},
+		},
+		{
+			// only a Square reaches describe's interface
+			name:       "interface call with VTA",
+			algorithm:  VTA,
+			function:   "Circle.Area",
+			wantTarget: lib + "(Circle).Area",
+			wantRoots:  []string{lib + "Measure"},
+			wantCalls: map[string]map[string][]string{
+				lib + "Measure": {lib + "(Circle).Area": {"lib.go"}},
+			},
+		},
+		{
+			// but any Shape might, as far as CHA knows
+			name:       "interface call with CHA",
+			algorithm:  CHA,
+			function:   "(Circle).Area",
+			wantTarget: lib + "(Circle).Area",
+			wantRoots:  []string{app + "main", lib + "Measure"},
+			wantCalls: map[string]map[string][]string{
+				app + "main":     {app + "describe": {"main.go"}},
+				app + "describe": {lib + "(Circle).Area": {"main.go"}},
+				lib + "Measure":  {lib + "(Circle).Area": {"lib.go"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := trace(t, tt.algorithm, tt.function)
+			if got := g.Target(); got != tt.wantTarget {
+				t.Errorf("got target %s, want %s", got, tt.wantTarget)
+			}
+			if got := g.Roots(); !reflect.DeepEqual(got, tt.wantRoots) {
+				t.Errorf("got roots %v, want %v", got, tt.wantRoots)
+			}
+			if !reflect.DeepEqual(g.calls, tt.wantCalls) {
+				t.Errorf("got calls %v\nwant %v", g.calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestLookup(t *testing.T) {
+	prog, err := load(parent, Options{Dir: "testdata/calls", Tests: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		pkg, function string
+		want          string
+		wantErr       bool
+	}{
+		{"lib", "Target", lib + "Target", false},
+		{parent + "/lib", "Target", lib + "Target", false},
+		{"lib", "Square.Area", lib + "(Square).Area", false},
+		{"lib", "(*Square).Area", lib + "(Square).Area", false},
+		{"lib", "Shape.Area", "", true},
+		{"lib", "Missing", "", true},
+		{"lib", "Square.Missing", "", true},
+		{"nope", "Target", "", true},
+	}
+	for _, tt := range tests {
+		fns, err := lookup(prog, parent, tt.pkg, tt.function)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s %s: got error %v, want error %v", tt.pkg, tt.function, err, tt.wantErr)
+			continue
+		}
+		for _, fn := range fns {
+			if got := funcName(fn); got != tt.want {
+				t.Errorf("%s %s: got %s, want %s", tt.pkg, tt.function, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestTree(t *testing.T) {
+	g := trace(t, VTA, "Target")
+
+	if _, err := g.Tree([]string{app + "nope"}); err == nil {
+		t.Error("got no error for an unknown root")
+	}
+
+	tr, err := g.Tree([]string{TestRoot})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{TestRoot + " -> " + lib + "Target (test, files: lib_test.go)"}
+	got := []string{}
+	for _, edge := range tr.Broaden() {
+		got = append(got, edge.String())
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got edges %v, want %v", got, want)
+	}
+
+	tr, err = g.Tree(g.Roots())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(tr.PackageNames()); got != 7 {
+		t.Errorf("got %d functions, want 7: %v", got, tr.PackageNames())
+	}
+}
@@ -0,0 +1,18 @@
+package lib
+
+func Target() {}
+
+type Shape interface{ Area() int }
+
+type Square struct{}
+
+func (Square) Area() int {
+	Target()
+	return 1
+}
+
+type Circle struct{}
+
+func (Circle) Area() int { return 0 }
+
+func Measure() int { return Circle{}.Area() }
@@ -0,0 +1,5 @@
+package lib
+
+import "testing"
+
+func TestTarget(t *testing.T) { Target() }
@@ -0,0 +1,16 @@
+package main
+
+import (
+	"ex.com/calls/app/lib"
+	"ex.com/calls/runner"
+)
+
+func main() {
+	direct()
+	describe(lib.Square{})
+	runner.Run(func() { lib.Target() })
+}
+
+func direct() { lib.Target() }
+
+func describe(s lib.Shape) int { return s.Area() }
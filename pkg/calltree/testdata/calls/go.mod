@@ -0,0 +1,3 @@
+module ex.com/calls
+
+go 1.21
@@ -0,0 +1,6 @@
+package runner
+
+// Run calls f, from outside the parent directory.
+func Run(f func()) { call(f) }
+
+func call(f func()) { f() }
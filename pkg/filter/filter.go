@@ -0,0 +1,139 @@
+// Package filter selects packages by name, using glob, regular expression or
+// go-style patterns.
+package filter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Pattern matches package names. It is written one of three ways:
+//
+//   - "/expr/" is a regular expression, matched anywhere in the name unless
+//     anchored.
+//   - A pattern containing "..." is a go package pattern, as with the go
+//     command: "..." matches any string (including slashes), and a trailing
+//     "/..." also matches the package before it.
+//   - Anything else is a glob: "*" matches within one path element, "?"
+//     matches one character, and "**" matches any number of elements, so
+//     "**/mocks" matches "mocks" and "a/b/mocks".
+type Pattern struct {
+	source string
+	re     *regexp.Regexp
+}
+
+// NewPattern parses a pattern.
+func NewPattern(source string) (*Pattern, error) {
+	var expr string
+	switch {
+	case len(source) > 2 && strings.HasPrefix(source, "/") && strings.HasSuffix(source, "/"):
+		expr = source[1 : len(source)-1]
+	case strings.Contains(source, "..."):
+		expr = regexp.QuoteMeta(source)
+		expr = strings.ReplaceAll(expr, `\.\.\.`, `.*`)
+		if strings.HasSuffix(expr, `/.*`) {
+			expr = strings.TrimSuffix(expr, `/.*`) + `(/.*)?`
+		}
+		expr = "^" + expr + "$"
+	default:
+		expr = "^" + globExpr(source) + "$"
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("bad pattern %q: %v", source, err)
+	}
+	return &Pattern{source: source, re: re}, nil
+}
+
+// globExpr translates a glob into a regular expression.
+func globExpr(glob string) string {
+	b := strings.Builder{}
+	for i := 0; i < len(glob); i++ {
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "/**"):
+			b.WriteString("(/.*)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case glob[i] == '*':
+			b.WriteString("[^/]*")
+		case glob[i] == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	return b.String()
+}
+
+func (p *Pattern) String() string {
+	return p.source
+}
+
+// Match reports whether any of the given names match the receiver.
+func (p *Pattern) Match(names ...string) bool {
+	for _, name := range names {
+		if p.re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter includes the packages that match any of its include patterns (or all
+// packages, if it has none), unless they match one of its exclude patterns.
+type Filter struct {
+	includes []*Pattern
+	excludes []*Pattern
+}
+
+// New returns a new filter from the given include and exclude patterns.
+func New(includes, excludes []string) (*Filter, error) {
+	f := Filter{}
+	for _, source := range includes {
+		p, err := NewPattern(source)
+		if err != nil {
+			return nil, err
+		}
+		f.includes = append(f.includes, p)
+	}
+	for _, source := range excludes {
+		p, err := NewPattern(source)
+		if err != nil {
+			return nil, err
+		}
+		f.excludes = append(f.excludes, p)
+	}
+	return &f, nil
+}
+
+// Empty returns whether the receiver has no patterns, and so includes every
+// package.
+func (f *Filter) Empty() bool {
+	return len(f.includes) == 0 && len(f.excludes) == 0
+}
+
+// Include reports whether the receiver includes a package, given any of its
+// names (e.g. its import path and its path relative to some parent).
+func (f *Filter) Include(names ...string) bool {
+	for _, p := range f.excludes {
+		if p.Match(names...) {
+			return false
+		}
+	}
+	if len(f.includes) == 0 {
+		return true
+	}
+	for _, p := range f.includes {
+		if p.Match(names...) {
+			return true
+		}
+	}
+	return false
+}
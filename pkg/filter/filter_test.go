@@ -0,0 +1,90 @@
+package filter
+
+import "testing"
+
+func TestPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		// go patterns
+		{"pkg/...", "pkg", true},
+		{"pkg/...", "pkg/tree", true},
+		{"pkg/...", "pkg/tree/sub", true},
+		{"pkg/...", "pkgs", false},
+		{".../generated/...", "a/generated", true},
+		{".../generated/...", "a/b/generated/c", true},
+		{".../generated/...", "generated", false},
+		{"pkg...", "pkgs/x", true},
+
+		// globs
+		{"pkg/tree", "pkg/tree", true},
+		{"pkg/tree", "pkg/tree/sub", false},
+		{"pkg/*", "pkg/tree", true},
+		{"pkg/*", "pkg/tree/sub", false},
+		{"pkg/t?ee", "pkg/tree", true},
+		{"pkg/t?ee", "pkg/t/ee", false},
+		{"**/mocks", "mocks", true},
+		{"**/mocks", "a/b/mocks", true},
+		{"**/mocks", "a/mocksx", false},
+		{"pkg/**", "pkg", true},
+		{"pkg/**", "pkg/a/b", true},
+		{"a**b", "a/x/b", true},
+		{"pkg.tree", "pkgxtree", false},
+
+		// regular expressions
+		{"/mock/", "a/mocks/b", true},
+		{"/^mock/", "a/mocks", false},
+		{"/(a|b)$/", "x/b", true},
+	}
+	for _, tt := range tests {
+		p, err := NewPattern(tt.pattern)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.pattern, err)
+		}
+		if got := p.Match(tt.name); got != tt.want {
+			t.Errorf("%s matching %s: got %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBadPattern(t *testing.T) {
+	if _, err := NewPattern("/(/"); err == nil {
+		t.Error("expected an error for a bad regular expression")
+	}
+	if _, err := New([]string{"ok"}, []string{"/[/"}); err == nil {
+		t.Error("expected an error for a bad exclude pattern")
+	}
+}
+
+func TestFilter(t *testing.T) {
+	tests := []struct {
+		name     string
+		includes []string
+		excludes []string
+		names    []string
+		want     bool
+	}{
+		{"empty", nil, nil, []string{"ex.com/a"}, true},
+		{"included", []string{"pkg/..."}, nil, []string{"ex.com/pkg/a", "pkg/a"}, true},
+		{"not included", []string{"pkg/..."}, nil, []string{"ex.com/cmd/a", "cmd/a"}, false},
+		{"excluded", nil, []string{"**/mocks"}, []string{"ex.com/pkg/mocks", "pkg/mocks"}, false},
+		{"exclude wins", []string{"pkg/..."}, []string{"**/mocks"}, []string{"ex.com/pkg/mocks", "pkg/mocks"}, false},
+		{"any include", []string{"cmd/...", "pkg/..."}, nil, []string{"ex.com/pkg/a", "pkg/a"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := New(tt.includes, tt.excludes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := f.Empty(); got != (len(tt.includes) == 0 && len(tt.excludes) == 0) {
+				t.Errorf("got Empty %v", got)
+			}
+			if got := f.Include(tt.names...); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+// Package lockfile records a tree's imports in a file, so that later trees can
+// be checked for imports that weren't there before.
+package lockfile
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spilliams/goraffe/pkg/tree"
+)
+
+const (
+	header    = "# goraffe import lockfile. Written by `goraffe freeze`, checked by `goraffe verify`."
+	arrow     = " -> "
+	parentKey = "# parent: "
+)
+
+// Import is one package importing another, by display name.
+type Import struct {
+	From string
+	To   string
+}
+
+func (i Import) String() string {
+	return i.From + arrow + i.To
+}
+
+// Lockfile is a sorted, de-duplicated set of imports. Packages are named
+// relative to the tree's parent directory, so that the file doesn't change if
+// the code moves.
+//
+// On disk, it's a line per import, like "internal/cli -> pkg/tree". Lines
+// starting with # are comments.
+type Lockfile struct {
+	Parent  string
+	Imports []Import
+}
+
+// FromTree returns a lockfile holding every edge in the tree.
+func FromTree(t *tree.Tree) *Lockfile {
+	l := Lockfile{Parent: t.ParentDirectory()}
+	for _, edge := range t.Broaden() {
+		l.Imports = append(l.Imports, Import{
+			From: t.DisplayName(edge.From),
+			To:   t.DisplayName(edge.To),
+		})
+	}
+	l.normalize()
+	return &l
+}
+
+// Read reads a lockfile.
+func Read(r io.Reader) (*Lockfile, error) {
+	l := Lockfile{}
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(text, parentKey) {
+			l.Parent = strings.TrimPrefix(text, parentKey)
+			continue
+		}
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		from, to, ok := strings.Cut(text, arrow)
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected \"from%sto\", got %q", line, arrow, text)
+		}
+		l.Imports = append(l.Imports, Import{From: strings.TrimSpace(from), To: strings.TrimSpace(to)})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	l.normalize()
+	return &l, nil
+}
+
+// ReadFile reads the named lockfile.
+func ReadFile(name string) (*Lockfile, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	l, err := Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return l, nil
+}
+
+// Write writes the receiver.
+func (l *Lockfile) Write(w io.Writer) error {
+	b := strings.Builder{}
+	b.WriteString(header + "\n")
+	if l.Parent != "" {
+		b.WriteString(parentKey + l.Parent + "\n")
+	}
+	for _, i := range l.Imports {
+		b.WriteString(i.String() + "\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteFile writes the receiver to the named file.
+func (l *Lockfile) WriteFile(name string) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := l.Write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Compare returns the imports in current that the receiver doesn't have
+// (added), and the imports the receiver has that current doesn't (stale).
+func (l *Lockfile) Compare(current *Lockfile) (added, stale []Import) {
+	locked := make(map[Import]bool)
+	for _, i := range l.Imports {
+		locked[i] = true
+	}
+	now := make(map[Import]bool)
+	for _, i := range current.Imports {
+		now[i] = true
+		if !locked[i] {
+			added = append(added, i)
+		}
+	}
+	for _, i := range l.Imports {
+		if !now[i] {
+			stale = append(stale, i)
+		}
+	}
+	return added, stale
+}
+
+// normalize sorts and de-duplicates the receiver's imports.
+func (l *Lockfile) normalize() {
+	sort.Slice(l.Imports, func(i, j int) bool {
+		if l.Imports[i].From != l.Imports[j].From {
+			return l.Imports[i].From < l.Imports[j].From
+		}
+		return l.Imports[i].To < l.Imports[j].To
+	})
+	unique := l.Imports[:0]
+	for i, imp := range l.Imports {
+		if i > 0 && imp == l.Imports[i-1] {
+			continue
+		}
+		unique = append(unique, imp)
+	}
+	l.Imports = unique
+}
@@ -0,0 +1,156 @@
+package lockfile
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spilliams/goraffe/pkg/tree"
+)
+
+func TestRead(t *testing.T) {
+	tests := []struct {
+		name       string
+		text       string
+		wantParent string
+		want       []Import
+		wantErr    bool
+	}{
+		{
+			name: "sorted and de-duplicated",
+			text: header + `
+# parent: ex.com
+pkg/b -> pkg/c
+
+# a comment
+cmd/a -> pkg/b
+  pkg/b   ->   pkg/c
+cmd/a -> pkg/a
+`,
+			wantParent: "ex.com",
+			want: []Import{
+				{"cmd/a", "pkg/a"},
+				{"cmd/a", "pkg/b"},
+				{"pkg/b", "pkg/c"},
+			},
+		},
+		{
+			name: "empty",
+			text: "",
+		},
+		{
+			name:    "no arrow",
+			text:    "cmd/a pkg/b\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := Read(strings.NewReader(tt.text))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if l.Parent != tt.wantParent {
+				t.Errorf("got parent %q, want %q", l.Parent, tt.wantParent)
+			}
+			if len(l.Imports) != 0 || len(tt.want) != 0 {
+				if !reflect.DeepEqual(l.Imports, tt.want) {
+					t.Errorf("got %v, want %v", l.Imports, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteRead(t *testing.T) {
+	loader := tree.NewFixtureLoader(
+		&tree.Package{ImportPath: "ex.com/cmd/a", Imports: []string{"ex.com/pkg/b", "ex.com/pkg/c"}},
+		&tree.Package{ImportPath: "ex.com/pkg/b", Imports: []string{"ex.com/pkg/c"}},
+		&tree.Package{ImportPath: "ex.com/pkg/c"},
+	)
+	tr := tree.NewTree("ex.com", loader)
+	if _, err := tr.AddRecursive("cmd/a"); err != nil {
+		t.Fatal(err)
+	}
+	l := FromTree(tr)
+
+	b := strings.Builder{}
+	if err := l.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	read, err := Read(strings.NewReader(b.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Lockfile{
+		Parent: "ex.com",
+		Imports: []Import{
+			{"cmd/a", "pkg/b"},
+			{"cmd/a", "pkg/c"},
+			{"pkg/b", "pkg/c"},
+		},
+	}
+	if !reflect.DeepEqual(read, want) {
+		t.Errorf("got %v, want %v", read, want)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	locked := &Lockfile{Imports: []Import{
+		{"cmd/a", "pkg/b"},
+		{"pkg/b", "pkg/c"},
+		{"pkg/b", "pkg/d"},
+	}}
+	tests := []struct {
+		name      string
+		current   []Import
+		wantAdded []Import
+		wantStale []Import
+	}{
+		{
+			name:    "same",
+			current: locked.Imports,
+		},
+		{
+			name: "added",
+			current: []Import{
+				{"cmd/a", "pkg/b"},
+				{"cmd/a", "pkg/c"},
+				{"pkg/b", "pkg/c"},
+				{"pkg/b", "pkg/d"},
+			},
+			wantAdded: []Import{{"cmd/a", "pkg/c"}},
+		},
+		{
+			name: "stale",
+			current: []Import{
+				{"cmd/a", "pkg/b"},
+				{"pkg/b", "pkg/d"},
+			},
+			wantStale: []Import{{"pkg/b", "pkg/c"}},
+		},
+		{
+			name: "both",
+			current: []Import{
+				{"cmd/a", "pkg/b"},
+				{"pkg/b", "pkg/e"},
+			},
+			wantAdded: []Import{{"pkg/b", "pkg/e"}},
+			wantStale: []Import{{"pkg/b", "pkg/c"}, {"pkg/b", "pkg/d"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			added, stale := locked.Compare(&Lockfile{Imports: tt.current})
+			if !reflect.DeepEqual(added, tt.wantAdded) {
+				t.Errorf("got added %v, want %v", added, tt.wantAdded)
+			}
+			if !reflect.DeepEqual(stale, tt.wantStale) {
+				t.Errorf("got stale %v, want %v", stale, tt.wantStale)
+			}
+		})
+	}
+}
@@ -0,0 +1,532 @@
+package render
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/awalterschulze/gographviz"
+)
+
+// sizes used by Layout, in pixels
+const (
+	margin     = 20.0
+	nodeGap    = 20.0
+	layerGap   = 60.0
+	charWidth  = 7.0
+	lineHeight = 15.0
+	padding    = 10.0
+	pointSize  = 8.0
+	dummyWidth = 10.0
+
+	pointsPerInch = 72.0
+)
+
+// how many times Layout sweeps up and down the layers, reordering them
+const orderSweeps = 8
+
+// node is a node of the graph being laid out. Dummy nodes stand in for the
+// bends of edges that cross more than one layer.
+type node struct {
+	name    string
+	lines   []string
+	shape   string
+	fills   []string
+	stroke  string
+	width   string
+	font    string
+	dashed  bool
+	tooltip string
+	dummy   bool
+
+	layer int
+	order float64
+	x, y  float64 // center
+	w, h  float64
+}
+
+// edge is an edge of the graph being laid out.
+type edge struct {
+	color     string
+	width     string
+	dashed    bool
+	arrowhead string
+	label     string
+	tooltip   string
+	// the nodes the edge passes through, from its source to its destination
+	path []int
+}
+
+// Layout lays a graph, written in the DOT language, out as an SVG image,
+// without graphviz. The layout is a simple layered one: nodes are ranked top to
+// bottom by the longest chain of edges leading to them, and each rank is
+// ordered to reduce crossings. Node labels, fill colors (including stripes),
+// outlines and tooltips are kept, as are edge colors, styles, arrowheads and
+// labels. Clusters are not drawn.
+func Layout(graph string) ([]byte, error) {
+	g, err := gographviz.Read([]byte(graph))
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := []*node{}
+	index := make(map[string]int)
+	for _, n := range g.Nodes.Sorted() {
+		index[n.Name] = len(nodes)
+		nodes = append(nodes, newNode(n))
+	}
+
+	edges := []*edge{}
+	for _, e := range g.Edges.Edges {
+		from, fromOK := index[e.Src]
+		to, toOK := index[e.Dst]
+		if !fromOK || !toOK || from == to {
+			continue
+		}
+		edges = append(edges, newEdge(e, from, to))
+	}
+
+	nodes = rank(nodes, edges)
+	layers := order(nodes, edges)
+	place(nodes, layers)
+	return draw(nodes, edges), nil
+}
+
+func newNode(n *gographviz.Node) *node {
+	attr := func(name string) string {
+		return unquote(n.Attrs[gographviz.Attr(name)])
+	}
+	label := n.Name
+	if l, ok := n.Attrs[gographviz.Attr("label")]; ok {
+		label = unquote(l)
+	}
+	nd := node{
+		name:    n.Name,
+		lines:   strings.Split(label, "\n"),
+		shape:   attr("shape"),
+		stroke:  attr("color"),
+		width:   attr("penwidth"),
+		font:    attr("fontcolor"),
+		dashed:  strings.Contains(attr("style"), "dashed"),
+		tooltip: attr("tooltip"),
+	}
+	if fill := attr("fillcolor"); fill != "" {
+		nd.fills = strings.Split(fill, ":")
+	}
+
+	switch nd.shape {
+	case "point":
+		nd.w, nd.h = pointSize, pointSize
+	default:
+		longest := 0
+		for _, line := range nd.lines {
+			if len(line) > longest {
+				longest = len(line)
+			}
+		}
+		nd.w = float64(longest)*charWidth + 2*padding
+		nd.h = float64(len(nd.lines))*lineHeight + padding
+		// like graphviz, take width and height (in inches) as minimums
+		if w, err := strconv.ParseFloat(attr("width"), 64); err == nil {
+			nd.w = math.Max(nd.w, w*pointsPerInch)
+		}
+		if h, err := strconv.ParseFloat(attr("height"), 64); err == nil {
+			nd.h = math.Max(nd.h, h*pointsPerInch)
+		}
+	}
+	return &nd
+}
+
+func newEdge(e *gographviz.Edge, from, to int) *edge {
+	attr := func(name string) string {
+		return unquote(e.Attrs[gographviz.Attr(name)])
+	}
+	return &edge{
+		color:     attr("color"),
+		width:     attr("penwidth"),
+		dashed:    strings.Contains(attr("style"), "dashed"),
+		arrowhead: attr("arrowhead"),
+		label:     attr("label"),
+		tooltip:   attr("tooltip"),
+		path:      []int{from, to},
+	}
+}
+
+// rank assigns each node a layer, such that edges point downwards, breaking
+// any cycles by pointing their last edge upwards instead. Edges spanning more
+// than one layer are given dummy nodes in the layers between. It returns the
+// nodes, with the dummies added.
+func rank(nodes []*node, edges []*edge) []*node {
+	// find the edges that close cycles, by depth-first search
+	out := make([][]int, len(nodes))
+	for i, e := range edges {
+		out[e.path[0]] = append(out[e.path[0]], i)
+	}
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(nodes))
+	reversed := make([]bool, len(edges))
+	var visit func(n int)
+	visit = func(n int) {
+		state[n] = visiting
+		for _, i := range out[n] {
+			to := edges[i].path[1]
+			switch state[to] {
+			case unvisited:
+				visit(to)
+			case visiting:
+				reversed[i] = true
+			}
+		}
+		state[n] = visited
+	}
+	for n := range nodes {
+		if state[n] == unvisited {
+			visit(n)
+		}
+	}
+
+	// rank by longest path, in topological order
+	down := make([][]int, len(nodes))
+	indegree := make([]int, len(nodes))
+	for i, e := range edges {
+		from, to := e.path[0], e.path[1]
+		if reversed[i] {
+			from, to = to, from
+		}
+		down[from] = append(down[from], to)
+		indegree[to]++
+	}
+	queue := []int{}
+	for n := range nodes {
+		if indegree[n] == 0 {
+			queue = append(queue, n)
+		}
+	}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		for _, to := range down[n] {
+			if nodes[n].layer+1 > nodes[to].layer {
+				nodes[to].layer = nodes[n].layer + 1
+			}
+			indegree[to]--
+			if indegree[to] == 0 {
+				queue = append(queue, to)
+			}
+		}
+	}
+
+	// bend long edges through dummy nodes
+	for i, e := range edges {
+		from, to := e.path[0], e.path[1]
+		if reversed[i] {
+			from, to = to, from
+		}
+		p := []int{from}
+		for layer := nodes[from].layer + 1; layer < nodes[to].layer; layer++ {
+			p = append(p, len(nodes))
+			nodes = append(nodes, &node{dummy: true, layer: layer, w: dummyWidth})
+		}
+		p = append(p, to)
+		if reversed[i] {
+			for l, r := 0, len(p)-1; l < r; l, r = l+1, r-1 {
+				p[l], p[r] = p[r], p[l]
+			}
+		}
+		e.path = p
+	}
+	return nodes
+}
+
+// order arranges the nodes of each layer to reduce edge crossings, by moving
+// each node towards the average position of its neighbors in the layer above
+// (or below), several times over. It returns the nodes of each layer, in
+// order.
+func order(nodes []*node, edges []*edge) [][]int {
+	layerCount := 0
+	for _, n := range nodes {
+		if n.layer+1 > layerCount {
+			layerCount = n.layer + 1
+		}
+	}
+	layers := make([][]int, layerCount)
+	for i, n := range nodes {
+		layers[n.layer] = append(layers[n.layer], i)
+	}
+
+	// neighbors in the layers above and below
+	above := make([][]int, len(nodes))
+	below := make([][]int, len(nodes))
+	for _, e := range edges {
+		for i := 0; i+1 < len(e.path); i++ {
+			a, b := e.path[i], e.path[i+1]
+			if nodes[a].layer > nodes[b].layer {
+				a, b = b, a
+			}
+			below[a] = append(below[a], b)
+			above[b] = append(above[b], a)
+		}
+	}
+
+	renumber := func(layer []int) {
+		for i, n := range layer {
+			nodes[n].order = float64(i)
+		}
+	}
+	for _, layer := range layers {
+		renumber(layer)
+	}
+
+	sweep := func(layer []int, neighbors [][]int) {
+		barycenter := make(map[int]float64, len(layer))
+		for _, n := range layer {
+			barycenter[n] = nodes[n].order
+			if len(neighbors[n]) == 0 {
+				continue
+			}
+			sum := 0.0
+			for _, m := range neighbors[n] {
+				sum += nodes[m].order
+			}
+			barycenter[n] = sum / float64(len(neighbors[n]))
+		}
+		sort.SliceStable(layer, func(i, j int) bool {
+			return barycenter[layer[i]] < barycenter[layer[j]]
+		})
+		renumber(layer)
+	}
+	for i := 0; i < orderSweeps; i++ {
+		for l := 1; l < len(layers); l++ {
+			sweep(layers[l], above)
+		}
+		for l := len(layers) - 2; l >= 0; l-- {
+			sweep(layers[l], below)
+		}
+	}
+	return layers
+}
+
+// place gives each node its coordinates: layers are rows, and each row is
+// centered.
+func place(nodes []*node, layers [][]int) {
+	widest := 0.0
+	for _, layer := range layers {
+		width := 0.0
+		for _, n := range layer {
+			width += nodes[n].w + nodeGap
+		}
+		widest = math.Max(widest, width-nodeGap)
+	}
+
+	y := margin
+	for _, layer := range layers {
+		width, height := 0.0, 0.0
+		for _, n := range layer {
+			width += nodes[n].w + nodeGap
+			height = math.Max(height, nodes[n].h)
+		}
+		x := margin + (widest-(width-nodeGap))/2
+		for _, n := range layer {
+			nodes[n].x = x + nodes[n].w/2
+			nodes[n].y = y + height/2
+			x += nodes[n].w + nodeGap
+		}
+		y += height + layerGap
+	}
+}
+
+// draw writes the laid-out graph as an SVG image.
+func draw(nodes []*node, edges []*edge) []byte {
+	width, height := 0.0, 0.0
+	for _, n := range nodes {
+		width = math.Max(width, n.x+n.w/2+margin)
+		height = math.Max(height, n.y+n.h/2+margin)
+	}
+
+	b := strings.Builder{}
+	fmt.Fprintf(&b, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%.0f\" height=\"%.0f\" viewBox=\"0 0 %.0f %.0f\" font-family=\"sans-serif\" font-size=\"12\">\n", width, height, width, height)
+
+	// markers for each color of arrowhead, and gradients for striped nodes
+	b.WriteString("<defs>\n")
+	markers := make(map[string]string)
+	for _, e := range edges {
+		key := e.arrowhead + " " + color(e.color, "black")
+		if _, ok := markers[key]; ok {
+			continue
+		}
+		id := fmt.Sprintf("m%d", len(markers))
+		markers[key] = id
+		c := color(e.color, "black")
+		switch e.arrowhead {
+		case "dot", "odot":
+			fill := c
+			if e.arrowhead == "odot" {
+				fill = "white"
+			}
+			fmt.Fprintf(&b, "<marker id=\"%s\" viewBox=\"0 0 10 10\" refX=\"9\" refY=\"5\" markerWidth=\"8\" markerHeight=\"8\" orient=\"auto\"><circle cx=\"5\" cy=\"5\" r=\"4\" fill=\"%s\" stroke=\"%s\"/></marker>\n", id, fill, c)
+		default:
+			fmt.Fprintf(&b, "<marker id=\"%s\" viewBox=\"0 0 10 10\" refX=\"10\" refY=\"5\" markerWidth=\"8\" markerHeight=\"8\" orient=\"auto\"><path d=\"M0,0 L10,5 L0,10 z\" fill=\"%s\"/></marker>\n", id, c)
+		}
+	}
+	gradients := make(map[string]string)
+	for _, n := range nodes {
+		if len(n.fills) < 2 {
+			continue
+		}
+		key := strings.Join(n.fills, ":")
+		if _, ok := gradients[key]; ok {
+			continue
+		}
+		id := fmt.Sprintf("g%d", len(gradients))
+		gradients[key] = id
+		fmt.Fprintf(&b, "<linearGradient id=\"%s\">", id)
+		for i, fill := range n.fills {
+			from := 100 * float64(i) / float64(len(n.fills))
+			to := 100 * float64(i+1) / float64(len(n.fills))
+			fmt.Fprintf(&b, "<stop offset=\"%.1f%%\" stop-color=\"%s\"/><stop offset=\"%.1f%%\" stop-color=\"%s\"/>", from, color(fill, "white"), to, color(fill, "white"))
+		}
+		b.WriteString("</linearGradient>\n")
+	}
+	b.WriteString("</defs>\n")
+
+	for _, e := range edges {
+		drawEdge(&b, nodes, e, markers[e.arrowhead+" "+color(e.color, "black")])
+	}
+	for _, n := range nodes {
+		if !n.dummy {
+			drawNode(&b, n, gradients)
+		}
+	}
+
+	b.WriteString("</svg>\n")
+	return []byte(b.String())
+}
+
+func drawEdge(b *strings.Builder, nodes []*node, e *edge, marker string) {
+	xs := make([]float64, len(e.path))
+	ys := make([]float64, len(e.path))
+	points := make([]string, len(e.path))
+	for i, n := range e.path {
+		nd := nodes[n]
+		xs[i], ys[i] = nd.x, nd.y
+		// leave and enter real nodes through their bottom or top edge
+		if !nd.dummy {
+			other := nodes[e.path[1]]
+			if i > 0 {
+				other = nodes[e.path[i-1]]
+			}
+			if other.y > nd.y {
+				ys[i] += nd.h / 2
+			} else {
+				ys[i] -= nd.h / 2
+			}
+		}
+		points[i] = fmt.Sprintf("%.1f,%.1f", xs[i], ys[i])
+	}
+
+	b.WriteString("<g>")
+	if e.tooltip != "" {
+		fmt.Fprintf(b, "<title>%s</title>", html.EscapeString(e.tooltip))
+	}
+	dash := ""
+	if e.dashed {
+		dash = " stroke-dasharray=\"5,3\""
+	}
+	fmt.Fprintf(b, "<polyline points=\"%s\" fill=\"none\" stroke=\"%s\" stroke-width=\"%s\"%s marker-end=\"url(#%s)\"/>",
+		strings.Join(points, " "), color(e.color, "black"), number(e.width, "1"), dash, marker)
+	if e.label != "" {
+		// label the middle segment, at its midpoint
+		m := len(e.path) / 2
+		x := (xs[m-1]+xs[m])/2 + 4
+		y := (ys[m-1] + ys[m]) / 2
+		for i, line := range strings.Split(e.label, "\n") {
+			fmt.Fprintf(b, "<text x=\"%.1f\" y=\"%.1f\">%s</text>", x, y+float64(i)*lineHeight, html.EscapeString(line))
+		}
+	}
+	b.WriteString("</g>\n")
+}
+
+func drawNode(b *strings.Builder, n *node, gradients map[string]string) {
+	b.WriteString("<g>")
+	title := n.tooltip
+	if title == "" {
+		title = strings.Join(n.lines, " ")
+	}
+	fmt.Fprintf(b, "<title>%s</title>", html.EscapeString(title))
+
+	dash := ""
+	if n.dashed {
+		dash = " stroke-dasharray=\"5,3\""
+	}
+	fill := "white"
+	if len(n.fills) == 1 {
+		fill = color(n.fills[0], "white")
+	} else if len(n.fills) > 1 {
+		fill = fmt.Sprintf("url(#%s)", gradients[strings.Join(n.fills, ":")])
+	}
+
+	switch n.shape {
+	case "point":
+		fmt.Fprintf(b, "<circle cx=\"%.1f\" cy=\"%.1f\" r=\"%.1f\" fill=\"black\"/>", n.x, n.y, n.w/2)
+		b.WriteString("</g>\n")
+		return
+	case "plaintext", "plain", "none":
+	default:
+		fmt.Fprintf(b, "<rect x=\"%.1f\" y=\"%.1f\" width=\"%.1f\" height=\"%.1f\" fill=\"%s\" stroke=\"%s\" stroke-width=\"%s\"%s/>",
+			n.x-n.w/2, n.y-n.h/2, n.w, n.h, fill, color(n.stroke, "black"), number(n.width, "1"), dash)
+	}
+
+	top := n.y - float64(len(n.lines))*lineHeight/2
+	for i, line := range n.lines {
+		fmt.Fprintf(b, "<text x=\"%.1f\" y=\"%.1f\" text-anchor=\"middle\" dominant-baseline=\"middle\" fill=\"%s\">%s</text>",
+			n.x, top+(float64(i)+0.5)*lineHeight, color(n.font, "black"), html.EscapeString(line))
+	}
+	b.WriteString("</g>\n")
+}
+
+// unquote returns the value of a DOT attribute, without its quotes and with
+// its escapes (like \n) resolved.
+func unquote(value string) string {
+	if len(value) < 2 || !strings.HasPrefix(value, "\"") || !strings.HasSuffix(value, "\"") {
+		return value
+	}
+	value = value[1 : len(value)-1]
+	b := strings.Builder{}
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' || i+1 == len(value) {
+			b.WriteByte(value[i])
+			continue
+		}
+		i++
+		switch value[i] {
+		case 'n', 'l', 'r':
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(value[i])
+		}
+	}
+	return b.String()
+}
+
+// color returns the given color, or the fallback if it's empty.
+func color(c, fallback string) string {
+	if c == "" {
+		return fallback
+	}
+	return html.EscapeString(c)
+}
+
+// number returns the given number, or the fallback if it isn't one.
+func number(n, fallback string) string {
+	if _, err := strconv.ParseFloat(n, 64); err != nil {
+		return fallback
+	}
+	return n
+}
@@ -0,0 +1,191 @@
+package render
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestUnquote(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{`plain`, `plain`},
+		{`"quoted"`, `quoted`},
+		{`"two\nlines"`, "two\nlines"},
+		{`"left\lright\r"`, "left\nright\n"},
+		{`"say \"hi\""`, `say "hi"`},
+		{`"back\\slash"`, `back\slash`},
+		{`"trailing\"`, `trailing\`},
+		{`"`, `"`},
+		{`"open`, `"open`},
+		{`""`, ``},
+	}
+	for _, tt := range tests {
+		if got := unquote(tt.in); got != tt.want {
+			t.Errorf("unquote(%s): got %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+// rankFixture returns n nodes, and an edge for each pair of node indexes.
+func rankFixture(n int, pairs ...[2]int) ([]*node, []*edge) {
+	nodes := make([]*node, n)
+	for i := range nodes {
+		nodes[i] = &node{}
+	}
+	edges := make([]*edge, len(pairs))
+	for i, p := range pairs {
+		edges[i] = &edge{path: []int{p[0], p[1]}}
+	}
+	return nodes, edges
+}
+
+func TestRank(t *testing.T) {
+	tests := []struct {
+		name       string
+		n          int
+		pairs      [][2]int
+		wantLayers []int // of every node, dummies included
+		wantPaths  [][]int
+	}{
+		{
+			name:       "chain",
+			n:          3,
+			pairs:      [][2]int{{0, 1}, {1, 2}},
+			wantLayers: []int{0, 1, 2},
+			wantPaths:  [][]int{{0, 1}, {1, 2}},
+		},
+		{
+			name:       "longest path",
+			n:          4,
+			pairs:      [][2]int{{0, 1}, {1, 2}, {2, 3}, {0, 3}},
+			wantLayers: []int{0, 1, 2, 3, 1, 2},
+			wantPaths:  [][]int{{0, 1}, {1, 2}, {2, 3}, {0, 4, 5, 3}},
+		},
+		{
+			// 2 -> 0 closes the cycle, so it points upwards, through a dummy
+			// in the middle layer, and still ends at 0
+			name:       "cycle",
+			n:          3,
+			pairs:      [][2]int{{0, 1}, {1, 2}, {2, 0}},
+			wantLayers: []int{0, 1, 2, 1},
+			wantPaths:  [][]int{{0, 1}, {1, 2}, {2, 3, 0}},
+		},
+		{
+			name:       "mutual imports",
+			n:          2,
+			pairs:      [][2]int{{0, 1}, {1, 0}},
+			wantLayers: []int{0, 1},
+			wantPaths:  [][]int{{0, 1}, {1, 0}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodes, edges := rankFixture(tt.n, tt.pairs...)
+			nodes = rank(nodes, edges)
+
+			layers := make([]int, len(nodes))
+			for i, n := range nodes {
+				layers[i] = n.layer
+				if n.dummy != (i >= tt.n) {
+					t.Errorf("node %d: got dummy %v", i, n.dummy)
+				}
+			}
+			if !reflect.DeepEqual(layers, tt.wantLayers) {
+				t.Errorf("got layers %v, want %v", layers, tt.wantLayers)
+			}
+			paths := make([][]int, len(edges))
+			for i, e := range edges {
+				paths[i] = e.path
+			}
+			if !reflect.DeepEqual(paths, tt.wantPaths) {
+				t.Errorf("got paths %v, want %v", paths, tt.wantPaths)
+			}
+		})
+	}
+}
+
+func TestLayout(t *testing.T) {
+	graph := `digraph G {
+	a [label="a\nfirst", fillcolor="red:green", style="filled", tooltip="a & <b>"];
+	b [shape="point"];
+	c [label="c", style="dashed", width="2"];
+	a -> b [label="1 < 2", style="dashed", arrowhead="dot"];
+	b -> c;
+	a -> c [color="blue"];
+	c -> a;
+}`
+	svg, err := Layout(graph)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	counts := map[string]int{}
+	titles := []string{}
+	texts := []string{}
+	d := xml.NewDecoder(bytes.NewReader(svg))
+	var last string
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("invalid SVG: %v\n%s", err, svg)
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			counts[tok.Name.Local]++
+			last = tok.Name.Local
+		case xml.CharData:
+			switch last {
+			case "title":
+				titles = append(titles, string(tok))
+			case "text":
+				texts = append(texts, string(tok))
+			}
+			last = ""
+		}
+	}
+
+	want := map[string]int{
+		"svg":            1,
+		"defs":           1,
+		"marker":         3, // dot, black and blue
+		"linearGradient": 1,
+		"polyline":       4,
+		"rect":           2,
+		"circle":         2, // b, and the dot marker
+	}
+	for name, n := range want {
+		if counts[name] != n {
+			t.Errorf("got %d <%s>, want %d", counts[name], name, n)
+		}
+	}
+	if !contains(titles, "a & <b>") {
+		t.Errorf("no node titled with a's tooltip in %q", titles)
+	}
+	for _, text := range []string{"a", "first", "c", "1 < 2"} {
+		if !contains(texts, text) {
+			t.Errorf("no text %q in %q", text, texts)
+		}
+	}
+}
+
+func TestLayoutInvalid(t *testing.T) {
+	if _, err := Layout("digraph {"); err == nil {
+		t.Error("got no error")
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+// Package render turns graphs written in the DOT language into images.
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// the image formats a graph can be rendered in
+const (
+	SVG = "svg"
+	PNG = "png"
+	PDF = "pdf"
+)
+
+// Formats lists the image formats a graph can be rendered in. Each is also the
+// extension of the files it is written to.
+var Formats = []string{SVG, PNG, PDF}
+
+// FormatOf returns the image format of the named file, from its extension.
+func FormatOf(name string) (string, error) {
+	format := strings.ToLower(strings.TrimPrefix(filepath.Ext(name), "."))
+	for _, f := range Formats {
+		if format == f {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("can't tell what format to render %s in; its extension should be one of: .%s", name, strings.Join(Formats, ", ."))
+}
+
+// File renders a graph, written in the DOT language, to the named file. The
+// file's extension says which format to render it in (see Formats).
+//
+// If graphviz's dot command is on the PATH, it does the rendering. Otherwise
+// SVGs are laid out by Layout instead, and the other formats fail.
+func File(graph, name string) error {
+	format, err := FormatOf(name)
+	if err != nil {
+		return err
+	}
+
+	dot, err := exec.LookPath("dot")
+	if err != nil {
+		if format != SVG {
+			return fmt.Errorf("rendering %s needs graphviz's `dot` command, which isn't on your PATH. Install graphviz (see https://graphviz.org/download/), or render an .%s instead", format, SVG)
+		}
+		logrus.Infof("graphviz's `dot` command isn't on your PATH, so laying %s out without it", name)
+		svg, err := Layout(graph)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(name, svg, 0o644)
+	}
+
+	logrus.Debugf("%s -T%s -o %s", dot, format, name)
+	var stderr bytes.Buffer
+	cmd := exec.Command(dot, "-T"+format, "-o", name)
+	cmd.Stdin = strings.NewReader(graph)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("dot: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
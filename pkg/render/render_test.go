@@ -0,0 +1,29 @@
+package render
+
+import "testing"
+
+func TestFormatOf(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{"graph.svg", SVG, false},
+		{"graph.png", PNG, false},
+		{"out/graph.pdf", PDF, false},
+		{"GRAPH.SVG", SVG, false},
+		{"graph.tar.png", PNG, false},
+		{"graph.dot", "", true},
+		{"graph", "", true},
+		{"svg", "", true},
+	}
+	for _, tt := range tests {
+		got, err := FormatOf(tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: got error %v, want error %v", tt.name, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+)
+
+// relative returns the violations with their files made relative to the
+// given directory (where possible), and with forward slashes.
+func relative(violations []Violation, base string) []Violation {
+	r := make([]Violation, 0, len(violations))
+	for _, v := range violations {
+		locs := make([]Location, 0, len(v.Locations))
+		for _, l := range v.Locations {
+			if rel, err := filepath.Rel(base, l.File); err == nil {
+				l.File = rel
+			}
+			l.File = filepath.ToSlash(l.File)
+			locs = append(locs, l)
+		}
+		v.Locations = locs
+		r = append(r, v)
+	}
+	return r
+}
+
+// WriteText writes a human-readable report of the violations, with file names
+// relative to the given directory.
+func WriteText(w io.Writer, violations []Violation, base string) error {
+	for _, v := range relative(violations, base) {
+		if _, err := fmt.Fprintf(w, "[%s] %s\n", v.Rule, v.Message); err != nil {
+			return err
+		}
+		for _, l := range v.Locations {
+			if _, err := fmt.Fprintf(w, "  %s:%d\n", l.File, l.Line); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := fmt.Fprintf(w, "%d violations\n", len(violations))
+	return err
+}
+
+// WriteJSON writes the violations as a JSON array, with file names relative to
+// the given directory.
+func WriteJSON(w io.Writer, violations []Violation, base string) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(relative(violations, base))
+}
+
+// the parts of a SARIF 2.1.0 log that goraffe writes.
+// See https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+// WriteSARIF writes the violations as a SARIF log, which code hosts can use to
+// annotate pull requests. File names are made relative to the given directory,
+// which should be the root of the repository.
+func WriteSARIF(w io.Writer, violations []Violation, base string) error {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "goraffe",
+			InformationURI: "https://github.com/spilliams/goraffe",
+			Rules: []sarifRule{
+				{ID: LayersRule, ShortDescription: sarifMessage{Text: "Packages may not import packages in higher layers"}},
+				{ID: ForbiddenRule, ShortDescription: sarifMessage{Text: "Forbidden import"}},
+			},
+		}},
+		Results: []sarifResult{},
+	}
+	for _, v := range relative(violations, base) {
+		result := sarifResult{
+			RuleID:  v.Rule,
+			Level:   "error",
+			Message: sarifMessage{Text: v.Message},
+		}
+		for _, l := range v.Locations {
+			result.Locations = append(result.Locations, sarifLocation{
+				PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{URI: l.File},
+					Region:           sarifRegion{StartLine: l.Line},
+				},
+			})
+		}
+		run.Results = append(run.Results, result)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	})
+}
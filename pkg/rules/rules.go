@@ -0,0 +1,252 @@
+// Package rules checks a tree's imports against architecture rules: layers
+// that may only import downwards, and imports that are forbidden outright.
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/spilliams/goraffe/pkg/filter"
+	"github.com/spilliams/goraffe/pkg/tree"
+)
+
+// the IDs of the kinds of rule
+const (
+	LayersRule    = "layers"
+	ForbiddenRule = "forbidden"
+)
+
+// Rules are the architecture rules for a tree, as read from a rules file:
+//
+//	{
+//	  "layers": [
+//	    {"name": "commands", "packages": ["cmd/..."]},
+//	    {"name": "cli", "packages": ["internal/..."]},
+//	    {"name": "library", "packages": ["pkg/..."]}
+//	  ],
+//	  "forbidden": [
+//	    {"from": "pkg/...", "to": "internal/...", "reason": "pkg is public"}
+//	  ],
+//	  "allowed": [
+//	    {"from": "pkg/legacy", "to": "internal/cli"}
+//	  ]
+//	}
+//
+// Layers are listed from the top down. A package belongs to the first layer
+// with a pattern that matches it, and may import packages in its own layer or
+// lower ones, but not higher ones. Packages in no layer are unconstrained.
+// Forbidden imports are violations wherever they appear. Allowed imports are
+// exceptions to both.
+//
+// Patterns are filter patterns (see filter.Pattern): go patterns like
+// "pkg/...", which matches pkg as well as the packages below it, globs like
+// "**/mocks", or /regular expressions/. They are matched against each
+// package's name relative to the tree's parent directory, and against its full
+// import path.
+type Rules struct {
+	Layers    []Layer  `json:"layers"`
+	Forbidden []Import `json:"forbidden"`
+	Allowed   []Import `json:"allowed"`
+}
+
+// Layer is a named group of packages.
+type Layer struct {
+	Name     string   `json:"name"`
+	Packages []string `json:"packages"`
+
+	patterns []*filter.Pattern
+}
+
+// Import describes a set of imports: from packages matching one pattern to
+// packages matching another.
+type Import struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Reason string `json:"reason,omitempty"`
+
+	from *filter.Pattern
+	to   *filter.Pattern
+}
+
+// Violation is an import that breaks one of the rules.
+type Violation struct {
+	Rule      string     `json:"rule"`
+	From      string     `json:"from"`
+	To        string     `json:"to"`
+	Message   string     `json:"message"`
+	Locations []Location `json:"locations"`
+}
+
+// Location is where an offending import is made.
+type Location struct {
+	File string `json:"file"`
+	Line int    `json:"line"`
+}
+
+// Load reads rules from the named file.
+func Load(name string) (*Rules, error) {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	r, err := Parse(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return r, nil
+}
+
+// Parse reads rules from a JSON document.
+func Parse(b []byte) (*Rules, error) {
+	r := Rules{}
+	if err := json.Unmarshal(b, &r); err != nil {
+		return nil, err
+	}
+
+	for i := range r.Layers {
+		l := &r.Layers[i]
+		if l.Name == "" {
+			l.Name = fmt.Sprintf("layer %d", i)
+		}
+		for _, source := range l.Packages {
+			p, err := filter.NewPattern(source)
+			if err != nil {
+				return nil, fmt.Errorf("layer %s: %v", l.Name, err)
+			}
+			l.patterns = append(l.patterns, p)
+		}
+	}
+	for _, imports := range [][]Import{r.Forbidden, r.Allowed} {
+		for i := range imports {
+			if err := imports[i].compile(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return &r, nil
+}
+
+func (i *Import) compile() error {
+	if i.From == "" || i.To == "" {
+		return fmt.Errorf("imports need both a \"from\" and a \"to\" pattern")
+	}
+	var err error
+	if i.from, err = filter.NewPattern(i.From); err != nil {
+		return err
+	}
+	i.to, err = filter.NewPattern(i.To)
+	return err
+}
+
+func (i *Import) match(t *tree.Tree, edge tree.Edge) bool {
+	return i.from.Match(names(t, edge.From)...) && i.to.Match(names(t, edge.To)...)
+}
+
+// names returns the names a package's patterns are matched against.
+func names(t *tree.Tree, name string) []string {
+	return []string{t.DisplayName(name), name}
+}
+
+// layer returns the index of the layer the named package belongs to, or -1.
+func (r *Rules) layer(t *tree.Tree, name string) int {
+	for i, l := range r.Layers {
+		for _, p := range l.patterns {
+			if p.Match(names(t, name)...) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// Evaluate returns every import in the tree that breaks the receiver's rules,
+// sorted by importer, then by import.
+func (r *Rules) Evaluate(t *tree.Tree) []Violation {
+	violations := []Violation{}
+	for _, edge := range t.Broaden() {
+		if r.allowed(t, edge) {
+			continue
+		}
+
+		from := t.DisplayName(edge.From)
+		to := t.DisplayName(edge.To)
+
+		fromLayer := r.layer(t, edge.From)
+		toLayer := r.layer(t, edge.To)
+		if fromLayer >= 0 && toLayer >= 0 && toLayer < fromLayer {
+			violations = append(violations, Violation{
+				Rule: LayersRule,
+				From: edge.From,
+				To:   edge.To,
+				Message: fmt.Sprintf("%s (layer %q) imports %s (layer %q), which is a higher layer",
+					from, r.Layers[fromLayer].Name, to, r.Layers[toLayer].Name),
+				Locations: locations(t, edge),
+			})
+		}
+
+		for _, f := range r.Forbidden {
+			if !f.match(t, edge) {
+				continue
+			}
+			message := fmt.Sprintf("%s imports %s, which is forbidden (%s -> %s)", from, to, f.From, f.To)
+			if f.Reason != "" {
+				message += ": " + f.Reason
+			}
+			violations = append(violations, Violation{
+				Rule:      ForbiddenRule,
+				From:      edge.From,
+				To:        edge.To,
+				Message:   message,
+				Locations: locations(t, edge),
+			})
+		}
+	}
+	return violations
+}
+
+func (r *Rules) allowed(t *tree.Tree, edge tree.Edge) bool {
+	for _, a := range r.Allowed {
+		if a.match(t, edge) {
+			return true
+		}
+	}
+	return false
+}
+
+// locations finds the import declarations behind an edge.
+func locations(t *tree.Tree, edge tree.Edge) []Location {
+	locs := []Location{}
+	pkg := t.Package(edge.From)
+	if pkg == nil {
+		return locs
+	}
+	for _, spec := range pkg.ImportSpecs {
+		if spec.Path != edge.To {
+			continue
+		}
+		found := false
+		for _, file := range edge.Files {
+			if file == spec.File {
+				found = true
+			}
+		}
+		if !found {
+			continue
+		}
+		locs = append(locs, Location{
+			File: filepath.Join(pkg.Dir, spec.File),
+			Line: spec.Line,
+		})
+	}
+	sort.Slice(locs, func(i, j int) bool {
+		if locs[i].File != locs[j].File {
+			return locs[i].File < locs[j].File
+		}
+		return locs[i].Line < locs[j].Line
+	})
+	return locs
+}
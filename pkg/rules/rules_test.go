@@ -0,0 +1,180 @@
+package rules
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/spilliams/goraffe/pkg/tree"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name       string
+		doc        string
+		wantErr    bool
+		wantLayers []string
+	}{
+		{
+			name:       "layers",
+			doc:        `{"layers": [{"name": "top", "packages": ["cmd/..."]}, {"packages": ["pkg/..."]}]}`,
+			wantLayers: []string{"top", "layer 1"},
+		},
+		{
+			name: "imports",
+			doc:  `{"forbidden": [{"from": "pkg/...", "to": "internal/..."}], "allowed": [{"from": "a", "to": "b"}]}`,
+		},
+		{
+			name:    "not JSON",
+			doc:     `layers: []`,
+			wantErr: true,
+		},
+		{
+			name:    "forbidden without to",
+			doc:     `{"forbidden": [{"from": "pkg/..."}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "allowed without from",
+			doc:     `{"allowed": [{"to": "pkg/..."}]}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := Parse([]byte(tt.doc))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			layers := []string{}
+			for _, l := range r.Layers {
+				layers = append(layers, l.Name)
+			}
+			if tt.wantLayers == nil {
+				tt.wantLayers = []string{}
+			}
+			if !reflect.DeepEqual(layers, tt.wantLayers) {
+				t.Errorf("got layers %v, want %v", layers, tt.wantLayers)
+			}
+		})
+	}
+}
+
+// fixtureTree returns a tree of the given packages, named relative to ex.com,
+// rooted at cmd/app. Each package imports the packages it maps to, one per line
+// from line 3 of its x.go.
+func fixtureTree(t *testing.T, imports map[string][]string) *tree.Tree {
+	t.Helper()
+	loader := tree.NewFixtureLoader()
+	for name, deps := range imports {
+		pkg := &tree.Package{ImportPath: "ex.com/" + name, Dir: "/src/" + name, GoFiles: []string{"x.go"}}
+		for i, dep := range deps {
+			pkg.Imports = append(pkg.Imports, "ex.com/"+dep)
+			pkg.ImportSpecs = append(pkg.ImportSpecs, tree.ImportSpec{File: "x.go", Line: i + 3, Path: "ex.com/" + dep})
+		}
+		loader.Add(pkg)
+	}
+	tr := tree.NewTree("ex.com", loader)
+	if _, err := tr.AddRecursive("cmd/app"); err != nil {
+		t.Fatal(err)
+	}
+	return tr
+}
+
+func TestEvaluate(t *testing.T) {
+	imports := map[string][]string{
+		"cmd/app":      {"internal/cli", "pkg/lib", "tools"},
+		"internal/cli": {"pkg/lib"},
+		"pkg/lib":      {"internal/cli"},
+		"pkg/legacy":   {"internal/cli"},
+		"tools":        {"cmd/app", "pkg/legacy"},
+	}
+	layers := `"layers": [
+		{"name": "commands", "packages": ["cmd/..."]},
+		{"name": "cli", "packages": ["internal/..."]},
+		{"name": "library", "packages": ["ex.com/pkg/..."]}
+	]`
+
+	type violation struct{ rule, from, to string }
+	tests := []struct {
+		name string
+		doc  string
+		want []violation
+	}{
+		{
+			name: "layers",
+			doc:  `{` + layers + `}`,
+			want: []violation{
+				{LayersRule, "ex.com/pkg/legacy", "ex.com/internal/cli"},
+				{LayersRule, "ex.com/pkg/lib", "ex.com/internal/cli"},
+			},
+		},
+		{
+			name: "forbidden",
+			doc:  `{"forbidden": [{"from": "pkg/...", "to": "internal/..."}, {"from": "...", "to": "cmd/app"}]}`,
+			want: []violation{
+				{ForbiddenRule, "ex.com/pkg/legacy", "ex.com/internal/cli"},
+				{ForbiddenRule, "ex.com/pkg/lib", "ex.com/internal/cli"},
+				{ForbiddenRule, "ex.com/tools", "ex.com/cmd/app"},
+			},
+		},
+		{
+			name: "allowed",
+			doc:  `{` + layers + `, "forbidden": [{"from": "pkg/...", "to": "internal/..."}], "allowed": [{"from": "pkg/legacy", "to": "internal/cli"}]}`,
+			want: []violation{
+				{LayersRule, "ex.com/pkg/lib", "ex.com/internal/cli"},
+				{ForbiddenRule, "ex.com/pkg/lib", "ex.com/internal/cli"},
+			},
+		},
+		{
+			name: "pattern matches whole names",
+			doc:  `{"forbidden": [{"from": "pkg", "to": "internal/cl"}]}`,
+			want: []violation{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := Parse([]byte(tt.doc))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []violation{}
+			for _, v := range r.Evaluate(fixtureTree(t, imports)) {
+				got = append(got, violation{v.Rule, v.From, v.To})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocations(t *testing.T) {
+	r, err := Parse([]byte(`{"forbidden": [{"from": "cmd/app", "to": "pkg/lib", "reason": "no"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	violations := r.Evaluate(fixtureTree(t, map[string][]string{
+		"cmd/app":      {"internal/cli", "pkg/lib"},
+		"internal/cli": nil,
+		"pkg/lib":      nil,
+	}))
+	if len(violations) != 1 {
+		t.Fatalf("got %d violations, want 1", len(violations))
+	}
+	v := violations[0]
+	wantMessage := "cmd/app imports pkg/lib, which is forbidden (cmd/app -> pkg/lib): no"
+	if v.Message != wantMessage {
+		t.Errorf("got message %q, want %q", v.Message, wantMessage)
+	}
+	want := []Location{{File: "/src/cmd/app/x.go", Line: 4}}
+	if !reflect.DeepEqual(v.Locations, want) {
+		t.Errorf("got locations %v, want %v", v.Locations, want)
+	}
+	rel := relative(violations, "/src")
+	if got := rel[0].Locations[0].File; got != "cmd/app/x.go" {
+		t.Errorf("got relative file %s, want cmd/app/x.go", got)
+	}
+}
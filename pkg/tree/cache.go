@@ -0,0 +1,176 @@
+package tree
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// cacheVersion is part of every cache key. Bump it whenever Package changes
+// shape, so that old entries aren't mistaken for new ones.
+const cacheVersion = "3"
+
+// DefaultCacheDir returns the directory goraffe keeps its package cache in.
+func DefaultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "goraffe"), nil
+}
+
+// CachedLoader is a Loader that remembers, on disk, what another Loader
+// returned. A cached package is reused for as long as the Go files in its
+// directory keep the same names, sizes and modification times.
+type CachedLoader struct {
+	loader    Loader
+	dir       string
+	namespace string
+}
+
+// NewCachedLoader returns a new CachedLoader that wraps the given loader and
+// keeps its entries in the given directory. The namespace should capture
+// anything besides the package's own files that affects how the wrapped loader
+// resolves packages (e.g. the module directory, or build tags), so that
+// differently-configured loaders don't share entries.
+func NewCachedLoader(loader Loader, dir, namespace string) *CachedLoader {
+	return &CachedLoader{
+		loader:    loader,
+		dir:       dir,
+		namespace: namespace,
+	}
+}
+
+type cachedFile struct {
+	Name    string
+	Size    int64
+	ModTime int64
+}
+
+type cacheEntry struct {
+	Files   []cachedFile
+	Package *Package
+}
+
+// Load returns the package with the given import path, from the cache if its
+// files haven't changed, or from the wrapped loader otherwise.
+func (c *CachedLoader) Load(importPath string) (*Package, error) {
+	entryPath := c.entryPath(importPath)
+
+	if entry, err := readCacheEntry(entryPath); err == nil {
+		files, err := stampFiles(entry.Package.Dir)
+		if err == nil && sameFiles(files, entry.Files) {
+			logrus.Debugf("cache hit for %s", importPath)
+			return entry.Package, nil
+		}
+	}
+
+	pkg, err := c.loader.Load(importPath)
+	if err != nil {
+		return pkg, err
+	}
+
+	// packages without a directory (e.g. fixtures) have nothing to check the
+	// cache against
+	if pkg.Dir == "" {
+		return pkg, nil
+	}
+
+	files, err := stampFiles(pkg.Dir)
+	if err != nil {
+		logrus.Debugf("not caching %s: %v", importPath, err)
+		return pkg, nil
+	}
+	if err := writeCacheEntry(entryPath, cacheEntry{Files: files, Package: pkg}); err != nil {
+		logrus.Debugf("not caching %s: %v", importPath, err)
+	}
+	return pkg, nil
+}
+
+func (c *CachedLoader) entryPath(importPath string) string {
+	sum := sha256.Sum256([]byte(cacheVersion + "\x00" + c.namespace + "\x00" + importPath))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+func readCacheEntry(entryPath string) (*cacheEntry, error) {
+	b, err := os.ReadFile(entryPath)
+	if err != nil {
+		return nil, err
+	}
+	entry := cacheEntry{}
+	if err := json.Unmarshal(b, &entry); err != nil {
+		return nil, err
+	}
+	if entry.Package == nil {
+		return nil, fmt.Errorf("cache entry %s has no package", entryPath)
+	}
+	return &entry, nil
+}
+
+// writeCacheEntry writes the entry to a temporary file first, so that readers
+// (perhaps in another process) never see half an entry.
+func writeCacheEntry(entryPath string, entry cacheEntry) error {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(entryPath), 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(entryPath), "entry-*")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), entryPath)
+}
+
+// stampFiles describes the Go files in the given directory.
+func stampFiles(dir string) ([]cachedFile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	files := []cachedFile{}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".go") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, cachedFile{
+			Name:    e.Name(),
+			Size:    info.Size(),
+			ModTime: info.ModTime().UnixNano(),
+		})
+	}
+	return files, nil
+}
+
+func sameFiles(a, b []cachedFile) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,99 @@
+package tree
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// countingLoader counts how many times each package is loaded.
+type countingLoader struct {
+	Loader
+	loads map[string]int
+}
+
+func (c *countingLoader) Load(importPath string) (*Package, error) {
+	c.loads[importPath]++
+	return c.Loader.Load(importPath)
+}
+
+func TestCachedLoader(t *testing.T) {
+	pkgDir := t.TempDir()
+	file := filepath.Join(pkgDir, "a.go")
+	write := func(content string, modTime time.Time) {
+		t.Helper()
+		if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(file, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	start := time.Now().Add(-time.Hour)
+	write("package a\n", start)
+
+	inner := &countingLoader{
+		Loader: NewFixtureLoader(
+			&Package{ImportPath: "ex.com/a", Dir: pkgDir, GoFiles: []string{"a.go"}},
+			&Package{ImportPath: "ex.com/nodir"},
+		),
+		loads: make(map[string]int),
+	}
+	cacheDir := t.TempDir()
+	cached := NewCachedLoader(inner, cacheDir, "ns")
+
+	steps := []struct {
+		name       string
+		change     func()
+		loader     *CachedLoader
+		importPath string
+		wantLoads  int
+	}{
+		{"first load", nil, cached, "ex.com/a", 1},
+		{"cache hit", nil, cached, "ex.com/a", 1},
+		{"file touched", func() { write("package a\n", start.Add(time.Minute)) }, cached, "ex.com/a", 2},
+		{"hit after touch", nil, cached, "ex.com/a", 2},
+		{"file resized", func() { write("package a // changed\n", start.Add(time.Minute)) }, cached, "ex.com/a", 3},
+		{"file added", func() {
+			if err := os.WriteFile(filepath.Join(pkgDir, "b.go"), []byte("package a\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}, cached, "ex.com/a", 4},
+		{"non-Go file added", func() {
+			if err := os.WriteFile(filepath.Join(pkgDir, "README"), []byte("hi\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}, cached, "ex.com/a", 4},
+		{"file removed", func() {
+			if err := os.Remove(filepath.Join(pkgDir, "b.go")); err != nil {
+				t.Fatal(err)
+			}
+		}, cached, "ex.com/a", 5},
+		{"other namespace", nil, NewCachedLoader(inner, cacheDir, "other"), "ex.com/a", 6},
+		{"no directory", nil, cached, "ex.com/nodir", 1},
+		{"no directory again", nil, cached, "ex.com/nodir", 2},
+	}
+	for _, step := range steps {
+		if step.change != nil {
+			step.change()
+		}
+		pkg, err := step.loader.Load(step.importPath)
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if pkg.ImportPath != step.importPath {
+			t.Errorf("%s: loaded %s, want %s", step.name, pkg.ImportPath, step.importPath)
+		}
+		if got := inner.loads[step.importPath]; got != step.wantLoads {
+			t.Errorf("%s: %s loaded %d times, want %d", step.name, step.importPath, got, step.wantLoads)
+		}
+	}
+}
+
+func TestCachedLoaderError(t *testing.T) {
+	cached := NewCachedLoader(NewFixtureLoader(), t.TempDir(), "ns")
+	if _, err := cached.Load("ex.com/missing"); err == nil {
+		t.Error("expected an error loading a missing package")
+	}
+}
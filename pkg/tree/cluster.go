@@ -0,0 +1,70 @@
+package tree
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/awalterschulze/gographviz"
+)
+
+// clusterPath returns the named package's directory, relative to the
+// receiver's parent directory, or "." if it is outside the parent directory
+// (or is the parent directory).
+func (t *Tree) clusterPath(name string) string {
+	if leaf := t.packageMap[name]; leaf != nil && leaf.xtest {
+		name = strings.TrimSuffix(name, "_test")
+	}
+	// collapsed nodes are named for the directory they stand for
+	name = strings.TrimSuffix(name, "/...")
+	// types go in their package's cluster
+	if i := strings.LastIndex(name, "."); t.types && i >= 0 {
+		name = name[:i] + "/" + name[i+1:]
+	}
+	if !strings.HasPrefix(name, t.parentDirectory+"/") {
+		return "."
+	}
+	return strings.TrimPrefix(name, t.parentDirectory+"/")
+}
+
+// addClusters adds a cluster to the graph for each directory holding one of
+// the given packages, nested inside the clusters of its parent directories. It
+// returns the name of the (sub)graph each package's node should be added to.
+func (t *Tree) addClusters(g *gographviz.Graph, topGraph string, packageNames []string) (map[string]string, error) {
+	// find every directory that needs a cluster
+	dirSet := make(map[string]bool)
+	for _, name := range packageNames {
+		for dir := path.Dir(t.clusterPath(name)); dir != "."; dir = path.Dir(dir) {
+			dirSet[dir] = true
+		}
+	}
+	dirs := make([]string, 0, len(dirSet))
+	for dir := range dirSet {
+		dirs = append(dirs, dir)
+	}
+	// parents sort before their children
+	sort.Strings(dirs)
+
+	clusters := map[string]string{".": topGraph}
+	for i, dir := range dirs {
+		clusters[dir] = fmt.Sprintf("cluster_D%d", i)
+		if err := g.AddSubGraph(clusters[path.Dir(dir)], clusters[dir], map[string]string{
+			"label": fmt.Sprintf("\"%s\"", dir),
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	graphs := make(map[string]string, len(packageNames))
+	for _, name := range packageNames {
+		// a package goes in its own directory's cluster, if that has one for
+		// its subdirectories, and otherwise in its parent directory's
+		dir := t.clusterPath(name)
+		if !dirSet[dir] {
+			dir = path.Dir(dir)
+		}
+		graphs[name] = clusters[dir]
+	}
+	return graphs, nil
+}
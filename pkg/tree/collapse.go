@@ -0,0 +1,238 @@
+package tree
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Collapse returns a new tree in which groups of packages are merged into one
+// node each. A package belongs to the group of the longest of the given
+// prefixes it falls under (e.g. "pkg/tree" or "pkg/tree/..." takes in pkg/tree
+// and every package below it). Failing that, if depth is more than zero,
+// packages inside the parent directory are grouped by their first depth path
+// elements. External test packages go with the package they test.
+//
+// A group node is named like "pkg/tree/...". Imports between groups are
+// de-duplicated, and their Count says how many package imports each one stands
+// for; imports within a group are dropped. A group that would only hold one
+// package is left alone.
+//
+// The new tree may be kept, grown and pruned like any other.
+func (t *Tree) Collapse(prefixes []string, depth int) *Tree {
+	c := NewTree(t.parentDirectory, t.loader)
+	c.includeTests = t.includeTests
+	c.includeExts = t.includeExts
+	c.jobs = t.jobs
+	c.platforms = t.platforms
+	c.legend = t.legend
+	c.cluster = t.cluster
+	c.colorBy = t.colorBy
+	c.sizeBy = t.sizeBy
+	c.coverage = t.coverage
+	c.filter = t.filter
+
+	groups := t.collapseGroups(prefixes, depth)
+	groupName := func(name string) string {
+		if g, ok := groups[name]; ok {
+			return g
+		}
+		return name
+	}
+
+	// make the nodes
+	for _, name := range t.sortedNames() {
+		leaf := t.packageMap[name]
+		if leaf == nil {
+			continue
+		}
+		g := groupName(name)
+		if g == name {
+			l := leaf.copy()
+			l.deps = []Edge{}
+			l.depAttrs = nil
+			c.packageMap[name] = l
+			continue
+		}
+
+		gLeaf, ok := c.packageMap[g]
+		if !ok {
+			gLeaf = NewLeaf(t.displayPath(strings.TrimSuffix(g, "/...")) + "/...")
+			gLeaf.deps = []Edge{}
+			c.packageMap[g] = gLeaf
+		}
+		gLeaf.root = gLeaf.root || leaf.root
+		gLeaf.keep = gLeaf.keep || leaf.keep
+		gLeaf.userKeep = gLeaf.userKeep || leaf.userKeep
+		if gLeaf.pkg == nil && leaf.pkg != nil {
+			gLeaf.pkg = &Package{ImportPath: g}
+		}
+	}
+
+	// merge the edges
+	for _, name := range t.sortedNames() {
+		leaf := t.packageMap[name]
+		if leaf == nil {
+			continue
+		}
+		from := groupName(name)
+		cLeaf := c.packageMap[from]
+		for _, edge := range leaf.deps {
+			to := groupName(edge.To)
+			if to == from {
+				continue
+			}
+			files := edge.Files
+			if from != name {
+				// a group's files come from several directories
+				files = make([]string, 0, len(edge.Files))
+				for _, file := range edge.Files {
+					files = append(files, path.Join(leaf.displayName, file))
+				}
+			}
+			symbols := edge.Symbols
+			if to != edge.To && symbols != nil {
+				// a group's symbols come from several packages
+				symbols = make([]Symbol, 0, len(edge.Symbols))
+				for _, s := range edge.Symbols {
+					symbols = append(symbols, Symbol{Name: path.Base(edge.To) + "." + s.Name, Uses: s.Uses})
+				}
+			}
+			cLeaf.collapseEdge(Edge{
+				From:        from,
+				To:          to,
+				Test:        edge.Test,
+				Files:       files,
+				Blank:       edge.Blank,
+				Dot:         edge.Dot,
+				Constraints: edge.Constraints,
+				Platforms:   edge.Platforms,
+				Symbols:     symbols,
+				Via:         edge.Via,
+			})
+		}
+	}
+
+	for _, leaf := range c.packageMap {
+		sort.Slice(leaf.deps, func(i, j int) bool {
+			return leaf.deps[i].To < leaf.deps[j].To
+		})
+	}
+
+	return c
+}
+
+// collapseGroups returns the name of the group each collapsing package belongs
+// to, by package name. Packages that aren't collapsed are left out.
+func (t *Tree) collapseGroups(prefixes []string, depth int) map[string]string {
+	// the prefixes may be named relative to the parent directory
+	fullPrefixes := make([]string, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		prefix = strings.TrimSuffix(path.Clean(prefix), "/...")
+		fullPrefixes = append(fullPrefixes, prefix)
+		if !strings.HasPrefix(prefix, t.parentDirectory+"/") {
+			fullPrefixes = append(fullPrefixes, path.Join(t.parentDirectory, prefix))
+		}
+	}
+	sort.Slice(fullPrefixes, func(i, j int) bool {
+		return len(fullPrefixes[i]) > len(fullPrefixes[j])
+	})
+
+	names := t.PackageNames()
+	groups := make(map[string]string)
+	// the packages in each group, not counting external test packages, which
+	// go with the package they test
+	members := make(map[string]map[string]bool)
+	for _, name := range names {
+		base := name
+		if leaf := t.packageMap[name]; leaf != nil && leaf.xtest {
+			base = strings.TrimSuffix(name, "_test")
+		}
+
+		group := ""
+		for _, prefix := range fullPrefixes {
+			if base == prefix || strings.HasPrefix(base, prefix+"/") {
+				group = prefix
+				break
+			}
+		}
+		if group == "" && depth > 0 && strings.HasPrefix(base, t.parentDirectory+"/") {
+			elems := strings.Split(strings.TrimPrefix(base, t.parentDirectory+"/"), "/")
+			if len(elems) >= depth {
+				group = path.Join(t.parentDirectory, path.Join(elems[:depth]...))
+			}
+		}
+		if group == "" {
+			continue
+		}
+
+		groups[name] = group + "/..."
+		if members[group+"/..."] == nil {
+			members[group+"/..."] = make(map[string]bool)
+		}
+		members[group+"/..."][base] = true
+	}
+
+	// leave alone the groups that would only hold one package
+	for name, group := range groups {
+		if len(members[group]) < 2 {
+			delete(groups, name)
+		}
+	}
+	return groups
+}
+
+// collapseEdge adds an edge to the receiver, which is part of a collapsed tree,
+// merging it with any edge it already has to the same package.
+func (l *Leaf) collapseEdge(edge Edge) {
+	for i := range l.deps {
+		e := &l.deps[i]
+		if e.To != edge.To {
+			continue
+		}
+		e.Count++
+		e.Test = e.Test && edge.Test
+		e.Blank = e.Blank || edge.Blank
+		e.Dot = e.Dot || edge.Dot
+		e.Files = unique(append(append([]string{}, e.Files...), edge.Files...))
+		sort.Strings(e.Files)
+		e.Constraints = unique(append(append([]string{}, e.Constraints...), edge.Constraints...))
+		sort.Strings(e.Constraints)
+		e.Platforms = unique(append(append([]string{}, e.Platforms...), edge.Platforms...))
+		sort.Strings(e.Platforms)
+		e.Symbols = mergeSymbols(e.Symbols, edge.Symbols)
+		e.Via = unique(append(append([]string{}, e.Via...), edge.Via...))
+		return
+	}
+	edge.Count = 1
+	l.deps = append(l.deps, edge)
+}
+
+// displayPath returns the given path relative to the receiver's parent
+// directory, if it is inside it.
+func (t *Tree) displayPath(p string) string {
+	if p == t.parentDirectory {
+		return path.Base(p)
+	}
+	return strings.TrimPrefix(p, t.parentDirectory+"/")
+}
+
+// edgeLabel returns the label for an edge: how many imports it stands for, if
+// it joins collapsed packages, and which platforms it exists on.
+func (t *Tree) edgeLabel(edge Edge) string {
+	parts := []string{}
+	if edge.Count > 1 {
+		parts = append(parts, fmt.Sprintf("%d imports", edge.Count))
+	}
+	if label := t.platformLabel(edge); label != "" {
+		parts = append(parts, label)
+	}
+	if label := symbolLabel(edge); label != "" {
+		parts = append(parts, label)
+	}
+	if label := viaLabel(edge); label != "" {
+		parts = append(parts, label)
+	}
+	return strings.Join(parts, "\\n")
+}
@@ -0,0 +1,108 @@
+package tree
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCollapse(t *testing.T) {
+	imports := map[string][]string{
+		"a":       {"pkg/t", "pkg/t/u", "pkg/t/v", "pkg/x/y"},
+		"pkg/t":   {"pkg/t/u"},
+		"pkg/t/u": {"pkg/t/v"},
+		"pkg/t/v": {"pkg/x/y"},
+		"pkg/x/y": nil,
+	}
+	tests := []struct {
+		name      string
+		prefixes  []string
+		depth     int
+		want      []string
+		wantCount map[[2]string]int
+	}{
+		{
+			name:     "prefix",
+			prefixes: []string{"pkg/t"},
+			want:     []string{"ex.com/a", "ex.com/pkg/t/...", "ex.com/pkg/x/y"},
+			wantCount: map[[2]string]int{
+				{"ex.com/a", "ex.com/pkg/t/..."}:       3,
+				{"ex.com/a", "ex.com/pkg/x/y"}:         1,
+				{"ex.com/pkg/t/...", "ex.com/pkg/x/y"}: 1,
+			},
+		},
+		{
+			name:     "full prefix with dots",
+			prefixes: []string{"ex.com/pkg/t/..."},
+			want:     []string{"ex.com/a", "ex.com/pkg/t/...", "ex.com/pkg/x/y"},
+		},
+		{
+			name:     "one sub-package",
+			prefixes: []string{"pkg/x"},
+			want:     []string{"ex.com/a", "ex.com/pkg/t", "ex.com/pkg/t/u", "ex.com/pkg/t/v", "ex.com/pkg/x/y"},
+		},
+		{
+			name:     "only itself",
+			prefixes: []string{"a"},
+			want:     []string{"ex.com/a", "ex.com/pkg/t", "ex.com/pkg/t/u", "ex.com/pkg/t/v", "ex.com/pkg/x/y"},
+		},
+		{
+			name:     "longest prefix",
+			prefixes: []string{"pkg", "pkg/t"},
+			want:     []string{"ex.com/a", "ex.com/pkg/t/...", "ex.com/pkg/x/y"},
+		},
+		{
+			name:  "depth 1",
+			depth: 1,
+			want:  []string{"ex.com/a", "ex.com/pkg/..."},
+			wantCount: map[[2]string]int{
+				{"ex.com/a", "ex.com/pkg/..."}: 4,
+			},
+		},
+		{
+			name:  "depth 2",
+			depth: 2,
+			want:  []string{"ex.com/a", "ex.com/pkg/t/...", "ex.com/pkg/x/y"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := fixtureTree(t, imports)
+			c := tr.Collapse(tt.prefixes, tt.depth)
+			if got := c.PackageNames(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+			for pair, want := range tt.wantCount {
+				edge, ok := c.Edge(pair[0], pair[1])
+				if !ok {
+					t.Errorf("no edge %s -> %s", pair[0], pair[1])
+					continue
+				}
+				if edge.Count != want {
+					t.Errorf("edge %s -> %s: got count %d, want %d", pair[0], pair[1], edge.Count, want)
+				}
+			}
+			// the original is left alone
+			if got := len(tr.PackageNames()); got != len(imports) {
+				t.Errorf("original tree has %d packages, want %d", got, len(imports))
+			}
+		})
+	}
+}
+
+func TestCollapseXTest(t *testing.T) {
+	// an external test package doesn't count as a second member of its
+	// package's group
+	loader := NewFixtureLoader(
+		&Package{ImportPath: "ex.com/a", Imports: []string{"ex.com/pkg/x/y"}},
+		&Package{ImportPath: "ex.com/pkg/x/y", XTestGoFiles: []string{"y_test.go"}, XTestImports: []string{"ex.com/pkg/x/y"}},
+	)
+	tr := NewTree("ex.com", loader)
+	tr.SetIncludeTests(true)
+	if _, err := tr.AddRecursive("a"); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"ex.com/a", "ex.com/pkg/x/y", "ex.com/pkg/x/y_test"}
+	if got := tr.Collapse([]string{"pkg/x"}, 0).PackageNames(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -7,7 +7,10 @@ const (
 	Orange = "#fcd92d"
 	Red    = "red"
 
+	AddedColor        = Green
 	BrokenColor       = Red
+	CycleColor        = Red
+	RemovedColor      = Red
 	RootColor         = Green
 	SingleParentColor = Orange
 	UserKeepColor     = Blue
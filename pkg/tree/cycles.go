@@ -0,0 +1,153 @@
+package tree
+
+import "sort"
+
+// SCCs returns the receiver's strongly-connected components that contain a
+// cycle: groups of packages that can each reach the others by following
+// imports. Each component is sorted, and the components are sorted by their
+// first package. Packages that aren't part of any cycle are left out.
+func (t *Tree) SCCs() [][]string {
+	s := sccState{
+		tree:    t,
+		index:   make(map[string]int),
+		lowlink: make(map[string]int),
+		onStack: make(map[string]bool),
+	}
+	for _, name := range t.sortedNames() {
+		if _, ok := s.index[name]; !ok {
+			s.connect(name)
+		}
+	}
+
+	components := [][]string{}
+	for _, component := range s.components {
+		if len(component) == 1 {
+			if _, ok := t.Edge(component[0], component[0]); !ok {
+				continue
+			}
+		}
+		sort.Strings(component)
+		components = append(components, component)
+	}
+	sort.Slice(components, func(i, j int) bool {
+		return components[i][0] < components[j][0]
+	})
+	return components
+}
+
+// sccState holds the bookkeeping for Tarjan's strongly-connected components
+// algorithm.
+type sccState struct {
+	tree       *Tree
+	next       int
+	index      map[string]int
+	lowlink    map[string]int
+	stack      []string
+	onStack    map[string]bool
+	components [][]string
+}
+
+func (s *sccState) connect(name string) {
+	s.index[name] = s.next
+	s.lowlink[name] = s.next
+	s.next++
+	s.stack = append(s.stack, name)
+	s.onStack[name] = true
+
+	for _, edge := range s.tree.packageMap[name].deps {
+		if to, ok := s.tree.packageMap[edge.To]; !ok || to == nil {
+			continue
+		}
+		if _, ok := s.index[edge.To]; !ok {
+			s.connect(edge.To)
+			if s.lowlink[edge.To] < s.lowlink[name] {
+				s.lowlink[name] = s.lowlink[edge.To]
+			}
+		} else if s.onStack[edge.To] && s.index[edge.To] < s.lowlink[name] {
+			s.lowlink[name] = s.index[edge.To]
+		}
+	}
+
+	if s.lowlink[name] != s.index[name] {
+		return
+	}
+	component := []string{}
+	for {
+		top := s.stack[len(s.stack)-1]
+		s.stack = s.stack[:len(s.stack)-1]
+		s.onStack[top] = false
+		component = append(component, top)
+		if top == name {
+			break
+		}
+	}
+	s.components = append(s.components, component)
+}
+
+// Cycles returns one import cycle from each of the receiver's SCCs: the
+// shortest cycle through the component's first package. Each cycle starts and
+// ends with that package.
+func (t *Tree) Cycles() [][]string {
+	cycles := [][]string{}
+	for _, component := range t.SCCs() {
+		start := component[0]
+		within := func(name string) bool { return contains(component, name) }
+
+		// the shortest cycle is the shortest way back to the start from any of
+		// the start's imports
+		var best []string
+		for _, edge := range t.packageMap[start].deps {
+			if !within(edge.To) {
+				continue
+			}
+			p := t.shortestPath(edge.To, start, within)
+			if p != nil && (best == nil || len(p) < len(best)) {
+				best = p
+			}
+		}
+		cycles = append(cycles, append([]string{start}, best...))
+	}
+	return cycles
+}
+
+// shortestPath returns the shortest chain of imports from one package to
+// another, through packages the given predicate allows, or nil if there isn't
+// one. The chain includes both ends.
+func (t *Tree) shortestPath(from, to string, allow func(string) bool) []string {
+	previous := map[string]string{from: ""}
+	queue := []string{from}
+	for len(queue) > 0 {
+		this := queue[0]
+		queue = queue[1:]
+		if this == to {
+			p := []string{}
+			for name := to; name != ""; name = previous[name] {
+				p = append([]string{name}, p...)
+			}
+			return p
+		}
+		leaf, ok := t.packageMap[this]
+		if !ok || leaf == nil {
+			continue
+		}
+		for _, edge := range leaf.deps {
+			if _, seen := previous[edge.To]; seen || !allow(edge.To) {
+				continue
+			}
+			previous[edge.To] = this
+			queue = append(queue, edge.To)
+		}
+	}
+	return nil
+}
+
+// cycleMembers maps each package in a cycle to the index of its SCC.
+func (t *Tree) cycleMembers() map[string]int {
+	members := make(map[string]int)
+	for i, component := range t.SCCs() {
+		for _, name := range component {
+			members[name] = i
+		}
+	}
+	return members
+}
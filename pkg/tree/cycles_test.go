@@ -0,0 +1,104 @@
+package tree
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSCCsAndCycles(t *testing.T) {
+	tests := []struct {
+		name       string
+		imports    map[string][]string
+		wantSCCs   [][]string
+		wantCycles [][]string
+	}{
+		{
+			name:       "no cycles",
+			imports:    diamond,
+			wantSCCs:   [][]string{},
+			wantCycles: [][]string{},
+		},
+		{
+			name: "one cycle",
+			imports: map[string][]string{
+				"a": {"b"},
+				"b": {"c"},
+				"c": {"b", "d"},
+				"d": nil,
+			},
+			wantSCCs:   [][]string{{"ex.com/b", "ex.com/c"}},
+			wantCycles: [][]string{{"ex.com/b", "ex.com/c", "ex.com/b"}},
+		},
+		{
+			name: "two components",
+			imports: map[string][]string{
+				"a": {"b", "c"},
+				"b": {"a"},
+				"c": {"d"},
+				"d": {"e"},
+				"e": {"c"},
+			},
+			wantSCCs: [][]string{
+				{"ex.com/a", "ex.com/b"},
+				{"ex.com/c", "ex.com/d", "ex.com/e"},
+			},
+			wantCycles: [][]string{
+				{"ex.com/a", "ex.com/b", "ex.com/a"},
+				{"ex.com/c", "ex.com/d", "ex.com/e", "ex.com/c"},
+			},
+		},
+		{
+			name: "shortest of several",
+			imports: map[string][]string{
+				"a": {"b", "d"},
+				"b": {"c"},
+				"c": {"a"},
+				"d": {"a"},
+			},
+			wantSCCs:   [][]string{{"ex.com/a", "ex.com/b", "ex.com/c", "ex.com/d"}},
+			wantCycles: [][]string{{"ex.com/a", "ex.com/d", "ex.com/a"}},
+		},
+		{
+			name: "self import",
+			imports: map[string][]string{
+				"a": {"a", "b"},
+				"b": nil,
+			},
+			wantSCCs:   [][]string{{"ex.com/a"}},
+			wantCycles: [][]string{{"ex.com/a", "ex.com/a"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := fixtureTree(t, tt.imports)
+			if got := tr.SCCs(); !reflect.DeepEqual(got, tt.wantSCCs) {
+				t.Errorf("SCCs: got %v, want %v", got, tt.wantSCCs)
+			}
+			if got := tr.Cycles(); !reflect.DeepEqual(got, tt.wantCycles) {
+				t.Errorf("Cycles: got %v, want %v", got, tt.wantCycles)
+			}
+		})
+	}
+}
+
+func TestTestImportCycle(t *testing.T) {
+	// b's own test files import a, which imports b
+	loader := NewFixtureLoader(
+		&Package{ImportPath: "ex.com/a", Name: "a", GoFiles: []string{"a.go"}, Imports: []string{"ex.com/b"}},
+		&Package{ImportPath: "ex.com/b", Name: "b", GoFiles: []string{"b.go"}, TestGoFiles: []string{"b_test.go"}, TestImports: []string{"ex.com/a"}},
+	)
+	for _, tests := range []bool{false, true} {
+		tr := NewTree("ex.com", loader)
+		tr.SetIncludeTests(tests)
+		if _, err := tr.AddRecursive("a"); err != nil {
+			t.Fatal(err)
+		}
+		want := [][]string{}
+		if tests {
+			want = [][]string{{"ex.com/a", "ex.com/b"}}
+		}
+		if got := tr.SCCs(); !reflect.DeepEqual(got, want) {
+			t.Errorf("tests %v: got %v, want %v", tests, got, want)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package tree
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Diff describes how one tree (the head) differs from another (the base).
+// Packages and imports are matched by import path.
+type Diff struct {
+	// AddedPackages lists the packages only in the head, sorted.
+	AddedPackages []string
+	// RemovedPackages lists the packages only in the base, sorted.
+	RemovedPackages []string
+	// AddedEdges lists the imports only in the head, sorted.
+	AddedEdges []Edge
+	// RemovedEdges lists the imports only in the base, sorted.
+	RemovedEdges []Edge
+
+	base *Tree
+	head *Tree
+}
+
+// NewDiff compares two trees.
+func NewDiff(base, head *Tree) *Diff {
+	d := Diff{
+		AddedPackages:   []string{},
+		RemovedPackages: []string{},
+		AddedEdges:      []Edge{},
+		RemovedEdges:    []Edge{},
+		base:            base,
+		head:            head,
+	}
+
+	for _, name := range head.sortedNames() {
+		if _, ok := base.packageMap[name]; !ok {
+			d.AddedPackages = append(d.AddedPackages, name)
+		}
+	}
+	for _, name := range base.sortedNames() {
+		if _, ok := head.packageMap[name]; !ok {
+			d.RemovedPackages = append(d.RemovedPackages, name)
+		}
+	}
+
+	for _, edge := range head.Broaden() {
+		if _, ok := base.Edge(edge.From, edge.To); !ok {
+			d.AddedEdges = append(d.AddedEdges, edge)
+		}
+	}
+	for _, edge := range base.Broaden() {
+		if _, ok := head.Edge(edge.From, edge.To); !ok {
+			d.RemovedEdges = append(d.RemovedEdges, edge)
+		}
+	}
+
+	return &d
+}
+
+// Empty returns whether the two trees have the same packages and imports.
+func (d *Diff) Empty() bool {
+	return len(d.AddedPackages) == 0 && len(d.RemovedPackages) == 0 &&
+		len(d.AddedEdges) == 0 && len(d.RemovedEdges) == 0
+}
+
+// displayName returns the name the head (or, failing that, the base) displays
+// the named package with.
+func (d *Diff) displayName(name string) string {
+	if _, ok := d.head.packageMap[name]; ok {
+		return d.head.DisplayName(name)
+	}
+	return d.base.DisplayName(name)
+}
+
+// Summary returns a one-line count of the receiver's changes.
+func (d *Diff) Summary() string {
+	return fmt.Sprintf("%d packages added, %d removed; %d imports added, %d removed",
+		len(d.AddedPackages),
+		len(d.RemovedPackages),
+		len(d.AddedEdges),
+		len(d.RemovedEdges),
+	)
+}
+
+// String lists the receiver's changes, one per line, with "+" for additions
+// and "-" for removals, followed by a summary.
+func (d *Diff) String() string {
+	b := strings.Builder{}
+	for _, name := range d.AddedPackages {
+		fmt.Fprintf(&b, "+ package %s\n", d.displayName(name))
+	}
+	for _, name := range d.RemovedPackages {
+		fmt.Fprintf(&b, "- package %s\n", d.displayName(name))
+	}
+	for _, edge := range d.AddedEdges {
+		fmt.Fprintf(&b, "+ %s -> %s\n", d.displayName(edge.From), d.displayName(edge.To))
+	}
+	for _, edge := range d.RemovedEdges {
+		fmt.Fprintf(&b, "- %s -> %s\n", d.displayName(edge.From), d.displayName(edge.To))
+	}
+	b.WriteString(d.Summary())
+	return b.String()
+}
+
+// Markdown describes the receiver's changes to imports in a sentence, followed
+// by lists of the imports added and removed, e.g. for commenting on a pull
+// request.
+func (d *Diff) Markdown() string {
+	b := strings.Builder{}
+	switch {
+	case len(d.AddedEdges) == 0 && len(d.RemovedEdges) == 0:
+		b.WriteString("This change doesn't add or remove any cross-package dependencies.\n")
+	case len(d.RemovedEdges) == 0:
+		fmt.Fprintf(&b, "This change adds %s.\n", dependencies(len(d.AddedEdges), "new "))
+	case len(d.AddedEdges) == 0:
+		fmt.Fprintf(&b, "This change removes %s.\n", dependencies(len(d.RemovedEdges), ""))
+	default:
+		fmt.Fprintf(&b, "This change adds %s, and removes %d.\n", dependencies(len(d.AddedEdges), "new "), len(d.RemovedEdges))
+	}
+
+	lists := []struct {
+		heading string
+		edges   []Edge
+	}{
+		{"Added", d.AddedEdges},
+		{"Removed", d.RemovedEdges},
+	}
+	for _, list := range lists {
+		if len(list.edges) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n%s:\n\n", list.heading)
+		for _, edge := range list.edges {
+			fmt.Fprintf(&b, "- `%s` → `%s`\n", d.displayName(edge.From), d.displayName(edge.To))
+		}
+	}
+	return b.String()
+}
+
+// dependencies returns e.g. "3 new cross-package dependencies".
+func dependencies(n int, adjective string) string {
+	if n == 1 {
+		return fmt.Sprintf("1 %scross-package dependency", adjective)
+	}
+	return fmt.Sprintf("%d %scross-package dependencies", n, adjective)
+}
+
+// Tree returns a new tree holding every package and import of both trees, for
+// drawing. It takes its settings from the head. In its Graphviz output, added
+// packages and imports are drawn in AddedColor, and removed ones are dashed and
+// drawn in RemovedColor.
+func (d *Diff) Tree() *Tree {
+	t := NewTree(d.head.parentDirectory, d.head.loader)
+	t.includeTests = d.head.includeTests
+	t.includeExts = d.head.includeExts
+	t.jobs = d.head.jobs
+	t.platforms = d.head.platforms
+
+	for name, leaf := range d.head.packageMap {
+		l := leaf.copy()
+		l.deps = append([]Edge{}, leaf.deps...)
+		t.packageMap[name] = l
+	}
+	for _, name := range d.RemovedPackages {
+		l := d.base.packageMap[name].copy()
+		l.deps = []Edge{}
+		l.attrs = map[string]string{
+			"color":    fmt.Sprintf("\"%s\"", RemovedColor),
+			"penwidth": "2",
+			"style":    "\"striped,dashed\"",
+		}
+		t.packageMap[name] = l
+	}
+	for _, name := range d.AddedPackages {
+		t.packageMap[name].attrs = map[string]string{
+			"color":    fmt.Sprintf("\"%s\"", AddedColor),
+			"penwidth": "2",
+		}
+	}
+
+	for _, edge := range d.AddedEdges {
+		t.packageMap[edge.From].setDepAttrs(edge.To, map[string]string{
+			"color":    fmt.Sprintf("\"%s\"", AddedColor),
+			"penwidth": "2",
+		})
+	}
+	for _, edge := range d.RemovedEdges {
+		leaf := t.packageMap[edge.From]
+		leaf.deps = append(leaf.deps, edge)
+		sort.Slice(leaf.deps, func(i, j int) bool {
+			return leaf.deps[i].To < leaf.deps[j].To
+		})
+		leaf.setDepAttrs(edge.To, map[string]string{
+			"color": fmt.Sprintf("\"%s\"", RemovedColor),
+			"style": "dashed",
+		})
+	}
+
+	return t
+}
+
+// setDepAttrs sets extra graphviz attributes for the receiver's import of the
+// named package.
+func (l *Leaf) setDepAttrs(to string, attrs map[string]string) {
+	depAttrs := make(map[string]map[string]string, len(l.depAttrs)+1)
+	for k, v := range l.depAttrs {
+		depAttrs[k] = v
+	}
+	depAttrs[to] = attrs
+	l.depAttrs = depAttrs
+}
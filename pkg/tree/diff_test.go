@@ -0,0 +1,76 @@
+package tree
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewDiff(t *testing.T) {
+	base := fixtureTree(t, diamond)
+	head := fixtureTree(t, map[string][]string{
+		"a": {"b", "c"},
+		"b": {"d", "f"},
+		"c": nil,
+		"d": {"e"},
+		"e": nil,
+		"f": nil,
+	})
+	d := NewDiff(base, head)
+
+	if want := []string{"ex.com/f"}; !reflect.DeepEqual(d.AddedPackages, want) {
+		t.Errorf("got added packages %v, want %v", d.AddedPackages, want)
+	}
+	if want := []string{}; !reflect.DeepEqual(d.RemovedPackages, want) {
+		t.Errorf("got removed packages %v, want %v", d.RemovedPackages, want)
+	}
+	edges := func(edges []Edge) []string {
+		r := []string{}
+		for _, e := range edges {
+			r = append(r, e.From+" -> "+e.To)
+		}
+		return r
+	}
+	if got, want := edges(d.AddedEdges), []string{"ex.com/b -> ex.com/f"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got added edges %v, want %v", got, want)
+	}
+	if got, want := edges(d.RemovedEdges), []string{"ex.com/c -> ex.com/d"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got removed edges %v, want %v", got, want)
+	}
+	if d.Empty() {
+		t.Error("diff should not be empty")
+	}
+
+	wantText := `+ package f
++ b -> f
+- c -> d
+1 packages added, 0 removed; 1 imports added, 1 removed`
+	if got := d.String(); got != wantText {
+		t.Errorf("got text:\n%s\nwant:\n%s", got, wantText)
+	}
+	if got := d.Markdown(); !strings.HasPrefix(got, "This change adds 1 new cross-package dependency, and removes 1.\n") {
+		t.Errorf("got markdown:\n%s", got)
+	}
+
+	// the diff's tree holds both sides, without changing either
+	if got, want := d.Tree().PackageNames(), []string{"ex.com/a", "ex.com/b", "ex.com/c", "ex.com/d", "ex.com/e", "ex.com/f"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got tree packages %v, want %v", got, want)
+	}
+	if _, ok := d.Tree().Edge("ex.com/c", "ex.com/d"); !ok {
+		t.Error("diff tree should have the removed edge")
+	}
+	if _, ok := head.Edge("ex.com/c", "ex.com/d"); ok {
+		t.Error("head should not have gained the removed edge")
+	}
+}
+
+func TestNewDiffSame(t *testing.T) {
+	d := NewDiff(fixtureTree(t, diamond), fixtureTree(t, diamond))
+	if !d.Empty() {
+		t.Errorf("expected an empty diff, got:\n%s", d)
+	}
+	want := "This change doesn't add or remove any cross-package dependencies.\n"
+	if got := d.Markdown(); got != want {
+		t.Errorf("got markdown %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,148 @@
+package tree
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Edge is one package importing another, along with what is known about that
+// import.
+type Edge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Test is whether To is only imported by From's test files.
+	Test bool `json:"test"`
+	// Files lists the files in From's directory that import To.
+	Files []string `json:"files"`
+	// Blank is whether any of those files import To as "_".
+	Blank bool `json:"blank"`
+	// Dot is whether any of those files import To as ".".
+	Dot bool `json:"dot"`
+	// Constraints lists the build constraints (//go:build expressions) of those
+	// files that have one.
+	Constraints []string `json:"constraints,omitempty"`
+	// Platforms lists the platforms on which From imports To, if the tree is
+	// the union of several platforms' trees.
+	Platforms []string `json:"platforms,omitempty"`
+	// Count is how many package imports the edge stands for, if the tree was
+	// collapsed (see Tree.Collapse).
+	Count int `json:"count,omitempty"`
+	// Symbols lists the exported identifiers of To that From uses, most used
+	// first, if they were loaded (see Tree.LoadSymbols).
+	Symbols []Symbol `json:"symbols,omitempty"`
+	// Via lists the ways From refers to To, if they are types (see
+	// Tree.Types), like "field deps", "embeds" or "method Edge result".
+	Via []string `json:"via,omitempty"`
+}
+
+func (e Edge) String() string {
+	facts := []string{}
+	if e.Test {
+		facts = append(facts, "test")
+	}
+	if e.Blank {
+		facts = append(facts, "blank")
+	}
+	if e.Dot {
+		facts = append(facts, "dot")
+	}
+	if len(e.Files) > 0 {
+		facts = append(facts, "files: "+strings.Join(e.Files, " "))
+	}
+	if len(e.Constraints) > 0 {
+		facts = append(facts, "constraints: "+strings.Join(e.Constraints, "; "))
+	}
+	if len(e.Platforms) > 0 {
+		facts = append(facts, "platforms: "+strings.Join(e.Platforms, " "))
+	}
+	if e.Count > 0 {
+		facts = append(facts, fmt.Sprintf("count: %d", e.Count))
+	}
+	if len(e.Symbols) > 0 {
+		names := make([]string, 0, len(e.Symbols))
+		for _, s := range e.Symbols {
+			names = append(names, fmt.Sprintf("%s(%d)", s.Name, s.Uses))
+		}
+		facts = append(facts, "symbols: "+strings.Join(names, " "))
+	}
+	if len(e.Via) > 0 {
+		facts = append(facts, "via: "+strings.Join(e.Via, "; "))
+	}
+	if len(facts) == 0 {
+		return fmt.Sprintf("%s -> %s", e.From, e.To)
+	}
+	return fmt.Sprintf("%s -> %s (%s)", e.From, e.To, strings.Join(facts, ", "))
+}
+
+// newEdges returns the edges from a package to each of its imports (or test
+// imports), sorted by import. Only the import specs from the given files are
+// used to describe the edges.
+func newEdges(from string, pkg *Package, imports, testImports, files []string) []Edge {
+	tos := unique(append(append([]string{}, imports...), testImports...))
+	sort.Strings(tos)
+
+	edges := make([]Edge, 0, len(tos))
+	for _, to := range tos {
+		e := Edge{
+			From:  from,
+			To:    to,
+			Test:  !contains(imports, to),
+			Files: []string{},
+		}
+		for _, spec := range pkg.ImportSpecs {
+			if spec.Path != to || !contains(files, spec.File) {
+				continue
+			}
+			if !contains(e.Files, spec.File) {
+				e.Files = append(e.Files, spec.File)
+			}
+			switch spec.Name {
+			case "_":
+				e.Blank = true
+			case ".":
+				e.Dot = true
+			}
+			if spec.Constraint != "" && !contains(e.Constraints, spec.Constraint) {
+				e.Constraints = append(e.Constraints, spec.Constraint)
+			}
+		}
+		sort.Strings(e.Files)
+		sort.Strings(e.Constraints)
+		edges = append(edges, e)
+	}
+	return edges
+}
+
+// Edges returns the receiver's imports, sorted by imported package.
+func (l *Leaf) Edges() []Edge {
+	return l.deps
+}
+
+// depNames returns the names of the packages the receiver imports.
+func (l *Leaf) depNames() []string {
+	names := make([]string, 0, len(l.deps))
+	for _, e := range l.deps {
+		names = append(names, e.To)
+	}
+	return names
+}
+
+// edge returns the receiver's import of the named package, if it has one.
+func (l *Leaf) edge(to string) (Edge, bool) {
+	for _, e := range l.deps {
+		if e.To == to {
+			return e, true
+		}
+	}
+	return Edge{}, false
+}
+
+// Edge returns the import of one package by another, if the receiver has one.
+func (t *Tree) Edge(from, to string) (Edge, bool) {
+	leaf, ok := t.packageMap[from]
+	if !ok || leaf == nil {
+		return Edge{}, false
+	}
+	return leaf.edge(to)
+}
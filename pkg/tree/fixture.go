@@ -0,0 +1,37 @@
+package tree
+
+import "fmt"
+
+// FixtureLoader is a Loader backed by an in-memory set of packages. It is
+// useful for building synthetic graphs, e.g. in tests.
+type FixtureLoader struct {
+	packages map[string]*Package
+}
+
+// NewFixtureLoader returns a new FixtureLoader that knows about the given
+// packages.
+func NewFixtureLoader(pkgs ...*Package) *FixtureLoader {
+	f := FixtureLoader{
+		packages: make(map[string]*Package),
+	}
+	for _, pkg := range pkgs {
+		f.Add(pkg)
+	}
+	return &f
+}
+
+// Add adds a package to the receiver, replacing any package with the same
+// import path. It is not safe to call Add while the receiver is in use by a
+// Tree.
+func (f *FixtureLoader) Add(pkg *Package) {
+	f.packages[pkg.ImportPath] = pkg
+}
+
+// Load returns the package with the given import path.
+func (f *FixtureLoader) Load(importPath string) (*Package, error) {
+	pkg, ok := f.packages[importPath]
+	if !ok {
+		return nil, fmt.Errorf("package %s not found in fixture", importPath)
+	}
+	return pkg, nil
+}
@@ -0,0 +1,81 @@
+package tree
+
+import (
+	"fmt"
+	"go/build"
+	"path"
+)
+
+// GOPATHLoader is a Loader that resolves packages with go/build, the way the go
+// command did before modules: from $GOROOT, $GOPATH and vendor directories.
+type GOPATHLoader struct {
+	ctx *build.Context
+}
+
+// NewGOPATHLoader returns a new GOPATHLoader that uses the given build context
+// (which decides the target platform and build tags). If ctx is nil,
+// build.Default is used.
+func NewGOPATHLoader(ctx *build.Context) *GOPATHLoader {
+	if ctx == nil {
+		ctx = &build.Default
+	}
+	return &GOPATHLoader{ctx: ctx}
+}
+
+type gopathError struct {
+	gopathPkg *build.Package
+	gopathErr error
+	vendorPkg *build.Package
+	vendorErr error
+}
+
+func (g gopathError) Error() string {
+	return fmt.Sprintf("{gopath error: %v; vendor error: %v}", g.gopathErr, g.vendorErr)
+}
+
+func (g gopathError) String() string {
+	s := fmt.Sprintf("gopath package:\n%+v\n", g.gopathPkg)
+	s += fmt.Sprintf("gopath error; %v\n", g.gopathErr)
+	s += fmt.Sprintf("vendor package:\n%+v\n", g.vendorPkg)
+	s += fmt.Sprintf("vendor error: %v", g.vendorErr)
+	return s
+}
+
+// Load returns the package with the given import path.
+func (g *GOPATHLoader) Load(importPath string) (*Package, error) {
+	gErr := gopathError{}
+
+	// first try the package without a source dir
+	gPkg, err := g.ctx.Import(importPath, "", 0)
+	if err == nil {
+		return fromBuildPackage(gPkg), nil
+	}
+	gErr.gopathErr = err
+	gErr.gopathPkg = gPkg
+
+	// then try importing it from vendor...
+	vPkg, err := g.ctx.Import(path.Join("vendor", importPath), "", 0)
+	if err == nil {
+		return fromBuildPackage(vPkg), nil
+	}
+	gErr.vendorErr = err
+	gErr.vendorPkg = vPkg
+
+	return nil, gErr
+}
+
+func fromBuildPackage(pkg *build.Package) *Package {
+	p := &Package{
+		ImportPath:   pkg.ImportPath,
+		Dir:          pkg.Dir,
+		Name:         pkg.Name,
+		GoFiles:      pkg.GoFiles,
+		TestGoFiles:  pkg.TestGoFiles,
+		XTestGoFiles: pkg.XTestGoFiles,
+		Imports:      pkg.Imports,
+		TestImports:  pkg.TestImports,
+		XTestImports: pkg.XTestImports,
+	}
+	readImportSpecs(p)
+	return p
+}
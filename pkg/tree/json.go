@@ -0,0 +1,178 @@
+package tree
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// JSONSchemaVersion is the version of the document written by Tree.JSON. It
+// changes whenever a field is removed or changes meaning; new fields may be
+// added without changing it.
+const JSONSchemaVersion = 1
+
+// jsonTree is the document written by Tree.JSON:
+//
+//	{
+//	  "schemaVersion": 1,
+//	  "parentDirectory": "github.com/spilliams/goraffe",
+//	  "platforms": ["linux/amd64", "windows/amd64"],
+//	  "nodes": [
+//	    {
+//	      "importPath": "github.com/spilliams/goraffe/internal/cli",
+//	      "displayName": "internal/cli",
+//	      "root": false,
+//	      "keep": false,
+//	      "userKeep": false,
+//	      "broken": false,
+//	      "xtest": false,
+//	      "importedBy": 1,
+//	      "imports": 2
+//	    }
+//	  ],
+//	  "edges": [
+//	    {
+//	      "from": "github.com/spilliams/goraffe/internal/cli",
+//	      "to": "github.com/spilliams/goraffe/pkg/tree",
+//	      "test": false,
+//	      "files": ["imports.go"],
+//	      "blank": false,
+//	      "dot": false,
+//	      "constraints": ["linux"],
+//	      "platforms": ["linux/amd64"],
+//	      "count": 3,
+//	      "symbols": [{"name": "NewTree", "uses": 2}, {"name": "Tree.Grow", "uses": 1}],
+//	      "via": ["field deps", "method Edges result"]
+//	    }
+//	  ]
+//	}
+//
+// Nodes are sorted by import path, and edges by importer and then import. Only
+// edges between two listed nodes are included. "platforms" (on the tree and on
+// edges) is only present for the union of several platforms' trees, and
+// "constraints" only for edges made by files with build constraints. "count"
+// is only present for collapsed trees, "symbols" only for trees whose symbols
+// were loaded, and "via" only for trees of types.
+type jsonTree struct {
+	SchemaVersion   int        `json:"schemaVersion"`
+	ParentDirectory string     `json:"parentDirectory"`
+	Platforms       []string   `json:"platforms,omitempty"`
+	Nodes           []jsonNode `json:"nodes"`
+	Edges           []Edge     `json:"edges"`
+}
+
+type jsonNode struct {
+	ImportPath  string `json:"importPath"`
+	DisplayName string `json:"displayName"`
+	Root        bool   `json:"root"`
+	Keep        bool   `json:"keep"`
+	UserKeep    bool   `json:"userKeep"`
+	Broken      bool   `json:"broken"`
+	XTest       bool   `json:"xtest"`
+	ImportedBy  int    `json:"importedBy"`
+	Imports     int    `json:"imports"`
+}
+
+// MarshalJSON encodes the receiver as described by JSON.
+func (t *Tree) MarshalJSON() ([]byte, error) {
+	t.countImports()
+
+	doc := jsonTree{
+		SchemaVersion:   JSONSchemaVersion,
+		ParentDirectory: t.parentDirectory,
+		Platforms:       t.platforms,
+		Nodes:           []jsonNode{},
+		Edges:           []Edge{},
+	}
+
+	for _, name := range t.sortedNames() {
+		leaf := t.packageMap[name]
+		if leaf == nil {
+			continue
+		}
+		doc.Nodes = append(doc.Nodes, jsonNode{
+			ImportPath:  name,
+			DisplayName: leaf.displayName,
+			Root:        leaf.root,
+			Keep:        leaf.keep,
+			UserKeep:    leaf.userKeep,
+			Broken:      leaf.IsBroken(),
+			XTest:       leaf.xtest,
+			ImportedBy:  leaf.importCount,
+			Imports:     len(leaf.deps),
+		})
+	}
+
+	for _, edge := range t.Broaden() {
+		if to, ok := t.packageMap[edge.To]; !ok || to == nil {
+			continue
+		}
+		doc.Edges = append(doc.Edges, edge)
+	}
+	return json.Marshal(doc)
+}
+
+// JSON returns the receiver as an indented JSON document, for use by other
+// tools. See JSONSchemaVersion for how the document's schema is versioned.
+func (t *Tree) JSON() (string, error) {
+	b, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// UnmarshalJSON decodes a document written by JSON into the receiver,
+// replacing its packages. Packages that weren't broken when the document was
+// written are given a Package holding only their import path. The receiver
+// keeps its loader, so it may be grown further.
+func (t *Tree) UnmarshalJSON(b []byte) error {
+	var doc jsonTree
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return err
+	}
+	if doc.SchemaVersion != JSONSchemaVersion {
+		return fmt.Errorf("unsupported schema version %d, expected %d", doc.SchemaVersion, JSONSchemaVersion)
+	}
+
+	if t.loader == nil {
+		t.loader = NewFixtureLoader()
+	}
+	t.packageMap = make(map[string]*Leaf)
+	t.parentDirectory = doc.ParentDirectory
+	t.platforms = doc.Platforms
+
+	for _, node := range doc.Nodes {
+		leaf := NewLeaf(node.DisplayName)
+		leaf.root = node.Root
+		leaf.keep = node.Keep
+		leaf.userKeep = node.UserKeep
+		leaf.xtest = node.XTest
+		leaf.deps = []Edge{}
+		if !node.Broken {
+			leaf.pkg = &Package{ImportPath: node.ImportPath}
+		}
+		t.packageMap[node.ImportPath] = leaf
+	}
+	for _, edge := range doc.Edges {
+		leaf, ok := t.packageMap[edge.From]
+		if !ok {
+			return fmt.Errorf("edge %s has no node for its importer", edge)
+		}
+		leaf.deps = append(leaf.deps, edge)
+	}
+	return nil
+}
+
+// ReadJSON returns a new tree from a document written by JSON.
+func ReadJSON(r io.Reader) (*Tree, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	t := NewTree("", NewFixtureLoader())
+	if err := json.Unmarshal(b, t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
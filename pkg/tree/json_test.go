@@ -0,0 +1,97 @@
+package tree
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	// ex.com/e isn't in the loader, so it's broken
+	specs := NewTree("ex.com", NewFixtureLoader(
+		specsPackage(t),
+		&Package{ImportPath: "ex.com/b"},
+		&Package{ImportPath: "ex.com/c"},
+		&Package{ImportPath: "ex.com/d"},
+	))
+	specs.SetIncludeTests(true)
+	specs.SetIncludeExts(true)
+	if _, err := specs.AddRecursive("a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := specs.Keep("c"); err != nil {
+		t.Fatal(err)
+	}
+
+	union := Union(map[string]*Tree{
+		"linux/amd64":   fixtureTree(t, map[string][]string{"a": {"b"}, "b": nil}),
+		"windows/amd64": fixtureTree(t, map[string][]string{"a": {"b", "c"}, "b": nil, "c": nil}),
+	})
+
+	tests := []struct {
+		name string
+		tree *Tree
+	}{
+		{"edge facts", specs},
+		{"platforms", union},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := tt.tree.JSON()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := ReadJSON(strings.NewReader(s))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got.PackageNames(), tt.tree.PackageNames()) {
+				t.Errorf("got packages %v, want %v", got.PackageNames(), tt.tree.PackageNames())
+			}
+			// an empty list and a missing one are the same document, so
+			// compare edges by their facts
+			if got, want := fmt.Sprint(got.Broaden()), fmt.Sprint(tt.tree.Broaden()); got != want {
+				t.Errorf("got edges\n%s\nwant\n%s", got, want)
+			}
+			if !reflect.DeepEqual(got.Platforms(), tt.tree.Platforms()) {
+				t.Errorf("got platforms %v, want %v", got.Platforms(), tt.tree.Platforms())
+			}
+			for name, want := range tt.tree.packageMap {
+				leaf := got.packageMap[name]
+				if leaf.displayName != want.displayName || leaf.root != want.root ||
+					leaf.keep != want.keep || leaf.userKeep != want.userKeep ||
+					leaf.xtest != want.xtest || leaf.IsBroken() != want.IsBroken() {
+					t.Errorf("%s: got %+v, want %+v", name, leaf, want)
+				}
+			}
+
+			again, err := got.JSON()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if again != s {
+				t.Errorf("re-encoded document differs:\n%s\nwant\n%s", again, s)
+			}
+		})
+	}
+}
+
+func TestReadJSONErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+	}{
+		{"not JSON", "{"},
+		{"schema version", `{"schemaVersion": 2}`},
+		{"edge without node", `{"schemaVersion": 1, "nodes": [], "edges": [{"from": "ex.com/a", "to": "ex.com/b"}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ReadJSON(strings.NewReader(tt.doc)); err == nil {
+				t.Error("got no error")
+			}
+		})
+	}
+}
@@ -1,22 +1,22 @@
 package tree
 
-import (
-	"fmt"
-	"go/build"
-)
+import "fmt"
 
 // Leaf contains helpful information about each package, like the package
 // itself, a friendly display name, and whether or not the tree wants to keep
 // it.
 type Leaf struct {
 	attrs       map[string]string
-	deps        []string
+	deps        []Edge                       // sorted by imported package
+	depAttrs    map[string]map[string]string // extra graphviz attributes, by imported package
 	displayName string
 	importCount int // the count of packages that import this one
 	keep        bool
-	pkg         *build.Package
-	root        bool // whether this is one of the named root packages
+	pkg         *Package
+	root        bool     // whether this is one of the named root packages
+	sources     *sources // counted from the package's files, once needed
 	userKeep    bool
+	xtest       bool // whether this is an external test package (foo_test)
 }
 
 // NewLeaf returns a new leaf.
@@ -36,12 +36,15 @@ func (l *Leaf) copy() *Leaf {
 	newLeaf := Leaf{
 		attrs:       l.attrs,
 		deps:        l.deps,
+		depAttrs:    l.depAttrs,
 		displayName: l.displayName,
 		importCount: l.importCount,
 		keep:        l.keep,
 		pkg:         l.pkg,
 		root:        l.root,
+		sources:     l.sources,
 		userKeep:    l.userKeep,
+		xtest:       l.xtest,
 	}
 	return &newLeaf
 }
@@ -61,13 +64,18 @@ func (l *Leaf) String() string {
 	if l.IsBroken() {
 		brokenString = ", broken"
 	}
-	return fmt.Sprintf("Leaf{%s, %d down, %d up%s%s%s}",
+	xtestString := ""
+	if l.xtest {
+		xtestString = ", xtest"
+	}
+	return fmt.Sprintf("Leaf{%s, %d down, %d up%s%s%s%s}",
 		l.displayName,
 		len(l.deps),
 		l.importCount,
 		keepString,
 		rootString,
 		brokenString,
+		xtestString,
 	)
 }
 
@@ -76,6 +84,11 @@ func (l *Leaf) ImportCount() int {
 	return l.importCount
 }
 
+// DisplayName returns the receiver's display name
+func (l *Leaf) DisplayName() string {
+	return l.displayName
+}
+
 // SetDisplayName modifies the receiver's display name
 func (l *Leaf) SetDisplayName(name string) {
 	l.displayName = name
@@ -91,6 +104,19 @@ func (l *Leaf) IsRoot() bool {
 	return l.root
 }
 
+// IsXTest returns whether the receiver is an external test package (one named
+// like "foo_test", in foo's directory).
+func (l *Leaf) IsXTest() bool {
+	return l.xtest
+}
+
+// IsTestImport returns whether the receiver imports the named package only from
+// its test files.
+func (l *Leaf) IsTestImport(name string) bool {
+	e, ok := l.edge(name)
+	return ok && e.Test
+}
+
 func (l *Leaf) attributes() map[string]string {
 	attr := map[string]string{
 		"label":     fmt.Sprintf("\"%s\\n%d up %d down\"", l.displayName, l.importCount, len(l.deps)),
@@ -98,6 +124,9 @@ func (l *Leaf) attributes() map[string]string {
 		"style":     "striped",
 		"fillcolor": l.fillColor(),
 	}
+	if l.xtest {
+		attr["style"] = "\"striped,dashed\""
+	}
 
 	for k, v := range l.attrs {
 		attr[k] = v
@@ -0,0 +1,29 @@
+package tree
+
+// Package is what a Loader knows about a single Go package: where it lives,
+// and what it imports.
+type Package struct {
+	ImportPath   string
+	Dir          string
+	Name         string
+	GoFiles      []string
+	TestGoFiles  []string
+	XTestGoFiles []string
+	Imports      []string
+	TestImports  []string
+	XTestImports []string
+	// ImportSpecs describes each import declaration in the package's files. It
+	// may be left empty, in which case the tree's edges won't know which files
+	// they come from.
+	ImportSpecs []ImportSpec
+}
+
+// Loader resolves import paths into Packages. The tree will ask a Loader for
+// each package it adds, first with the tree's parent directory prefixed to the
+// name, and then with the name as given. Load may be called from several
+// goroutines at once.
+type Loader interface {
+	// Load returns the package with the given import path, or an error if
+	// there is no such package (or it could not be read).
+	Load(importPath string) (*Package, error)
+}
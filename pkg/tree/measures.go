@@ -0,0 +1,273 @@
+package tree
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// the measures that nodes can be colored and sized by
+const (
+	FanIn       = "fan-in"      // how many of the tree's packages import the package
+	FanOut      = "fan-out"     // how many packages the package imports
+	LinesOfCode = "loc"         // the lines in the package's (non-test) Go files
+	FileCount   = "files"       // the number of the package's (non-test) Go files
+	Instability = "instability" // Ce / (Ca + Ce), as in Metrics
+	Coverage    = "coverage"    // the fraction of statements covered by tests
+)
+
+// Measures lists the measures that nodes can be colored and sized by.
+var Measures = []string{FanIn, FanOut, LinesOfCode, FileCount, Instability, Coverage}
+
+// HeatColors is the palette nodes are colored from, from coolest to hottest.
+var HeatColors = []string{"#ffffcc", "#ffeda0", "#fed976", "#feb24c", "#fd8d3c", "#fc4e2a", "#e31a1c", "#bd0026", "#800026"}
+
+// UnmeasuredColor is the color of nodes a measure has no value for, like
+// packages missing from a coverage profile.
+const UnmeasuredColor = "lightgrey"
+
+// SetColorBy modifies the receiver to color the nodes of its Graphviz output
+// by the given measure (see Measures), from the coolest of HeatColors for the
+// lowest value to the hottest for the highest. Coverage is the other way
+// around, so that poorly-covered packages stand out. An empty measure turns
+// this off.
+func (t *Tree) SetColorBy(measure string) error {
+	if err := checkMeasure(measure); err != nil {
+		return err
+	}
+	t.colorBy = measure
+	return nil
+}
+
+// SetSizeBy modifies the receiver to size the nodes of its Graphviz output by
+// the given measure (see Measures), from the default size for the lowest value
+// to the largest for the highest. An empty measure turns this off.
+func (t *Tree) SetSizeBy(measure string) error {
+	if err := checkMeasure(measure); err != nil {
+		return err
+	}
+	t.sizeBy = measure
+	return nil
+}
+
+// SetCoverage gives the receiver the test coverage of its packages, by import
+// path, for coloring and sizing nodes by Coverage. See ReadCoverProfile.
+func (t *Tree) SetCoverage(coverage map[string]float64) {
+	t.coverage = coverage
+}
+
+func checkMeasure(measure string) error {
+	if measure == "" || contains(Measures, measure) {
+		return nil
+	}
+	return fmt.Errorf("unknown measure %q, must be one of: %s", measure, strings.Join(Measures, ", "))
+}
+
+// measure returns the value of the given measure for each of the receiver's
+// packages that has one. countImports must have been called first.
+func (t *Tree) measure(measure string) map[string]float64 {
+	values := make(map[string]float64)
+	if measure == Instability {
+		// only production imports between packages count, and external test
+		// packages don't have a value
+		for _, m := range t.coupling() {
+			values[m.ImportPath] = m.Instability
+		}
+		return values
+	}
+	for name, leaf := range t.packageMap {
+		if leaf == nil {
+			continue
+		}
+		switch measure {
+		case FanIn:
+			values[name] = float64(leaf.importCount)
+		case FanOut:
+			values[name] = float64(len(leaf.deps))
+		case FileCount:
+			if leaf.pkg != nil {
+				values[name] = float64(len(leaf.pkg.GoFiles))
+			}
+		case LinesOfCode:
+			if leaf.pkg != nil && leaf.pkg.Dir != "" {
+				values[name] = float64(leaf.countSources().lines)
+			}
+		case Coverage:
+			if c, ok := t.coverage[name]; ok {
+				values[name] = c
+			}
+		}
+	}
+	return values
+}
+
+// scale maps each value onto [0, 1], from the lowest value to the highest.
+func scale(values map[string]float64) map[string]float64 {
+	first := true
+	var lo, hi float64
+	for _, v := range values {
+		if first || v < lo {
+			lo = v
+		}
+		if first || v > hi {
+			hi = v
+		}
+		first = false
+	}
+
+	scaled := make(map[string]float64, len(values))
+	for name, v := range values {
+		scaled[name] = 0
+		if hi > lo {
+			scaled[name] = (v - lo) / (hi - lo)
+		}
+	}
+	return scaled
+}
+
+// measureAttributes returns the graphviz attributes that color and size each
+// package's node by the receiver's measures, by package name.
+func (t *Tree) measureAttributes() map[string]map[string]string {
+	attrs := make(map[string]map[string]string)
+	if t.colorBy == "" && t.sizeBy == "" {
+		return attrs
+	}
+	for name := range t.packageMap {
+		attrs[name] = make(map[string]string)
+	}
+
+	if t.colorBy != "" {
+		values := t.measure(t.colorBy)
+		heat := scale(values)
+		for name, a := range attrs {
+			a["style"] = "filled"
+			if t.packageMap[name].xtest {
+				a["style"] = "\"filled,dashed\""
+			}
+			h, ok := heat[name]
+			if !ok {
+				a["fillcolor"] = fmt.Sprintf("\"%s\"", UnmeasuredColor)
+				continue
+			}
+			if t.colorBy == Coverage {
+				h = 1 - h
+			}
+			a["fillcolor"] = fmt.Sprintf("\"%s\"", HeatColors[int(h*float64(len(HeatColors)-1)+0.5)])
+			if h > 0.6 {
+				a["fontcolor"] = "white"
+			}
+		}
+		for name, v := range values {
+			leaf := t.packageMap[name]
+			attrs[name]["label"] = fmt.Sprintf("\"%s\\n%d up %d down\\n%s: %s\"", leaf.displayName, leaf.importCount, len(leaf.deps), t.colorBy, formatMeasure(t.colorBy, v))
+		}
+	}
+
+	if t.sizeBy != "" {
+		for name, s := range scale(t.measure(t.sizeBy)) {
+			attrs[name]["width"] = strconv.FormatFloat(0.75+2.25*s, 'f', 2, 64)
+			attrs[name]["height"] = strconv.FormatFloat(0.5+1.5*s, 'f', 2, 64)
+		}
+	}
+	return attrs
+}
+
+func formatMeasure(measure string, v float64) string {
+	switch measure {
+	case Instability:
+		return strconv.FormatFloat(v, 'f', 2, 64)
+	case Coverage:
+		return strconv.FormatFloat(100*v, 'f', 1, 64) + "%"
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// countLines returns how many lines the package's (non-test) Go files have.
+// Files that can't be read are skipped.
+func countLines(pkg *Package) int {
+	if pkg.Dir == "" {
+		return 0
+	}
+	lines := 0
+	for _, file := range pkg.GoFiles {
+		b, err := os.ReadFile(filepath.Join(pkg.Dir, file))
+		if err != nil {
+			continue
+		}
+		lines += bytes.Count(b, []byte("\n"))
+	}
+	return lines
+}
+
+// ReadCoverProfile reads a coverage profile, as written by
+// `go test -coverprofile`, and returns the fraction of each package's
+// statements that were covered, by import path.
+func ReadCoverProfile(r io.Reader) (map[string]float64, error) {
+	type counts struct {
+		statements int
+		covered    bool
+	}
+	blocks := make(map[string]*counts)
+	packages := make(map[string][]string)
+
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "mode:") {
+			continue
+		}
+		// e.g. "example.com/mod/pkg/file.go:12.34,15.2 3 1"
+		fields := strings.Fields(text)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("line %d: expected \"file:block statements count\", got %q", line, text)
+		}
+		file, _, ok := strings.Cut(fields[0], ":")
+		if !ok {
+			return nil, fmt.Errorf("line %d: no block in %q", line, fields[0])
+		}
+		statements, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		count, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+
+		// profiles merged from several runs may list a block more than once
+		b, ok := blocks[fields[0]]
+		if !ok {
+			b = &counts{statements: statements}
+			blocks[fields[0]] = b
+			pkg := path.Dir(file)
+			packages[pkg] = append(packages[pkg], fields[0])
+		}
+		b.covered = b.covered || count > 0
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	coverage := make(map[string]float64, len(packages))
+	for pkg, keys := range packages {
+		total, covered := 0, 0
+		for _, key := range keys {
+			total += blocks[key].statements
+			if blocks[key].covered {
+				covered += blocks[key].statements
+			}
+		}
+		if total > 0 {
+			coverage[pkg] = float64(covered) / float64(total)
+		}
+	}
+	return coverage, nil
+}
@@ -0,0 +1,132 @@
+package tree
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadCoverProfile(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile string
+		want    map[string]float64
+		wantErr bool
+	}{
+		{
+			name: "packages",
+			profile: `mode: set
+ex.com/a/a.go:3.10,5.2 2 1
+ex.com/a/a.go:7.10,9.2 2 0
+ex.com/a/b.go:3.10,5.2 4 1
+ex.com/b/b.go:3.10,5.2 1 0
+`,
+			want: map[string]float64{"ex.com/a": 0.75, "ex.com/b": 0},
+		},
+		{
+			name: "merged runs",
+			profile: `mode: count
+ex.com/a/a.go:3.10,5.2 2 0
+ex.com/a/a.go:7.10,9.2 2 0
+ex.com/a/a.go:3.10,5.2 2 3
+`,
+			want: map[string]float64{"ex.com/a": 0.5},
+		},
+		{
+			name: "no statements",
+			profile: `mode: set
+ex.com/a/a.go:3.10,5.2 0 0
+`,
+			want: map[string]float64{},
+		},
+		{
+			name:    "too few fields",
+			profile: "ex.com/a/a.go:3.10,5.2 2\n",
+			wantErr: true,
+		},
+		{
+			name:    "no block",
+			profile: "ex.com/a/a.go 2 1\n",
+			wantErr: true,
+		},
+		{
+			name:    "bad count",
+			profile: "ex.com/a/a.go:3.10,5.2 2 x\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadCoverProfile(strings.NewReader(tt.profile))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMeasureInstability(t *testing.T) {
+	// a's test files import c, and b's external test package imports a;
+	// neither counts
+	loader := NewFixtureLoader(
+		&Package{ImportPath: "ex.com/a", Imports: []string{"ex.com/b"}, TestImports: []string{"ex.com/c"}},
+		&Package{ImportPath: "ex.com/b", Imports: []string{"ex.com/c"}, XTestImports: []string{"ex.com/a"}},
+		&Package{ImportPath: "ex.com/c"},
+	)
+	tr := NewTree("ex.com", loader)
+	tr.SetIncludeTests(true)
+	if _, err := tr.AddRecursive("a"); err != nil {
+		t.Fatal(err)
+	}
+	tr.countImports()
+
+	want := map[string]float64{"ex.com/a": 1, "ex.com/b": 0.5, "ex.com/c": 0}
+	got := tr.measure(Instability)
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for name, w := range want {
+		if math.Abs(got[name]-w) > 1e-9 {
+			t.Errorf("%s: got %v, want %v", name, got[name], w)
+		}
+	}
+	for _, m := range tr.Metrics() {
+		if got[m.ImportPath] != m.Instability {
+			t.Errorf("%s: measured %v, but Metrics says %v", m.ImportPath, got[m.ImportPath], m.Instability)
+		}
+	}
+}
+
+func TestMeasureLinesOfCode(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.go"), []byte("package a\n\nfunc A() {}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tr := NewTree("ex.com", NewFixtureLoader(
+		&Package{ImportPath: "ex.com/a", Dir: dir, GoFiles: []string{"a.go"}, Imports: []string{"ex.com/b"}},
+		&Package{ImportPath: "ex.com/b"},
+	))
+	if _, err := tr.AddRecursive("a"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the counts outlive the files
+	tr.CountSources()
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]float64{"ex.com/a": 3}
+	if got := tr.measure(LinesOfCode); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
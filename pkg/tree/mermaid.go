@@ -0,0 +1,87 @@
+package tree
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// the Mermaid classes used for leaves, in the order they're applied. A leaf in
+// several classes gets the styles of the last one.
+var mermaidClasses = []struct {
+	name  string
+	style string
+	has   func(l *Leaf) bool
+}{
+	{"userKeep", "fill:" + UserKeepColor, func(l *Leaf) bool { return l.userKeep }},
+	{"root", "fill:" + RootColor, func(l *Leaf) bool { return l.root }},
+	{"singleParent", "fill:" + SingleParentColor, func(l *Leaf) bool { return l.importCount == 1 }},
+	{"broken", "fill:" + BrokenColor, func(l *Leaf) bool { return l.IsBroken() }},
+	{"xtest", "stroke-dasharray:5 5", func(l *Leaf) bool { return l.xtest }},
+}
+
+// Mermaid returns the tree's representation as a Mermaid flowchart, as for
+// embedding in Markdown. Leaves are colored with the same colors as Graphviz
+// uses, and test-only imports are dotted.
+// See https://mermaid.js.org/syntax/flowchart.html for more information.
+func (t *Tree) Mermaid() (string, error) {
+	t.countImports()
+
+	names := t.nodeNames()
+
+	var b strings.Builder
+	b.WriteString("flowchart TD\n")
+
+	// add package nodes
+	packageNames := make([]string, 0, len(names))
+	for _, packageName := range t.sortedNames() {
+		leaf := t.packageMap[packageName]
+		if leaf == nil {
+			continue
+		}
+		packageNames = append(packageNames, packageName)
+		label := fmt.Sprintf("%s<br/>%d up %d down", leaf.displayName, leaf.importCount, len(leaf.deps))
+		fmt.Fprintf(&b, "    %s[\"%s\"]\n", names[packageName], mermaidEscape(label))
+	}
+
+	// add import edges
+	for _, edge := range t.Broaden() {
+		if to, ok := t.packageMap[edge.To]; !ok || to == nil {
+			continue
+		}
+		arrow := "-->"
+		if edge.Test {
+			arrow = "-.->"
+		}
+		if label := t.edgeLabel(edge); label != "" {
+			label = strings.ReplaceAll(label, "\\n", "<br/>")
+			arrow += fmt.Sprintf("|\"%s\"|", mermaidEscape(label))
+		}
+		fmt.Fprintf(&b, "    %s %s %s\n", names[edge.From], arrow, names[edge.To])
+	}
+
+	// color the nodes
+	for _, class := range mermaidClasses {
+		fmt.Fprintf(&b, "    classDef %s %s\n", class.name, class.style)
+	}
+	for _, class := range mermaidClasses {
+		members := []string{}
+		for _, packageName := range packageNames {
+			if class.has(t.packageMap[packageName]) {
+				members = append(members, names[packageName])
+			}
+		}
+		if len(members) == 0 {
+			continue
+		}
+		sort.Strings(members)
+		fmt.Fprintf(&b, "    class %s %s\n", strings.Join(members, ","), class.name)
+	}
+
+	return b.String(), nil
+}
+
+// mermaidEscape makes a string safe to use inside a quoted Mermaid label.
+func mermaidEscape(s string) string {
+	return strings.ReplaceAll(s, "\"", "#quot;")
+}
@@ -0,0 +1,71 @@
+package tree
+
+import (
+	"testing"
+)
+
+func TestMermaid(t *testing.T) {
+	// ex.com/e isn't in the loader, so it's broken
+	tr := NewTree("ex.com", NewFixtureLoader(
+		specsPackage(t),
+		&Package{ImportPath: "ex.com/b"},
+		&Package{ImportPath: "ex.com/c"},
+		&Package{ImportPath: "ex.com/d"},
+	))
+	tr.SetIncludeTests(true)
+	tr.SetIncludeExts(true)
+	if _, err := tr.AddRecursive("a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Keep("c"); err != nil {
+		t.Fatal(err)
+	}
+	tr.packageMap["ex.com/b"].SetDisplayName(`b "quoted"`)
+	tr.packageMap["ex.com/a"].deps[0].Via = []string{`field "b"`, "embeds"}
+
+	want := `flowchart TD
+    N0["a<br/>1 up 3 down"]
+    N1["a_test<br/>0 up 2 down"]
+    N2["b #quot;quoted#quot;<br/>1 up 0 down"]
+    N3["c<br/>1 up 0 down"]
+    N4["d<br/>1 up 0 down"]
+    N5["ex.com/e<br/>1 up 0 down"]
+    N0 -->|"field #quot;b#quot;<br/>embeds"| N2
+    N0 --> N3
+    N0 -.-> N4
+    N1 -.-> N0
+    N1 -.-> N5
+    classDef userKeep fill:#76E1FE
+    classDef root fill:green
+    classDef singleParent fill:#fcd92d
+    classDef broken fill:red
+    classDef xtest stroke-dasharray:5 5
+    class N3 userKeep
+    class N0 root
+    class N0,N2,N3,N4,N5 singleParent
+    class N5 broken
+    class N1 xtest
+`
+	got, err := tr.Mermaid()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestMermaidEscape(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"plain", "plain"},
+		{`"quoted"`, "#quot;quoted#quot;"},
+		{`a "b" c`, "a #quot;b#quot; c"},
+	}
+	for _, tt := range tests {
+		if got := mermaidEscape(tt.in); got != tt.want {
+			t.Errorf("mermaidEscape(%q): got %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package tree
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"math"
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Metrics are Robert C. Martin's package metrics, for one package of a tree.
+// Only production imports between the tree's packages are counted; test
+// imports and external test packages are left out.
+type Metrics struct {
+	ImportPath  string `json:"importPath"`
+	DisplayName string `json:"displayName"`
+	// Afferent is how many of the tree's packages import this one (Ca).
+	Afferent int `json:"afferent"`
+	// Efferent is how many packages this one imports (Ce).
+	Efferent int `json:"efferent"`
+	// Instability is Ce / (Ca + Ce), from 0 (only imported) to 1 (only
+	// importing). It is 0 for a package with no imports either way.
+	Instability float64 `json:"instability"`
+	// Interfaces is how many interface types the package declares.
+	Interfaces int `json:"interfaces"`
+	// Types is how many named types the package declares, interfaces
+	// included.
+	Types int `json:"types"`
+	// Abstractness is Interfaces / Types, or 0 if there are no types.
+	Abstractness float64 `json:"abstractness"`
+	// Distance is how far the package is from the "main sequence", where
+	// Abstractness + Instability = 1: 0 is on it, and 1 is as far away as can
+	// be (either concrete and depended on, or abstract and unused).
+	Distance float64 `json:"distance"`
+}
+
+// Metrics returns the metrics of each of the receiver's packages, except broken
+// ones, sorted by import path. Abstractness is found by parsing the packages'
+// files (see CountSources), so is only known for packages with a directory on
+// disk.
+func (t *Tree) Metrics() []Metrics {
+	metrics := t.coupling()
+	for i := range metrics {
+		m := &metrics[i]
+		s := t.packageMap[m.ImportPath].countSources()
+		m.Interfaces, m.Types = s.interfaces, s.types
+		if m.Types > 0 {
+			m.Abstractness = float64(m.Interfaces) / float64(m.Types)
+		}
+		m.Distance = math.Abs(m.Abstractness + m.Instability - 1)
+	}
+	return metrics
+}
+
+// coupling returns the metrics of each of the receiver's packages like Metrics
+// does, but only fills in the ones that come from imports: Afferent, Efferent
+// and Instability.
+func (t *Tree) coupling() []Metrics {
+	afferent := make(map[string]int)
+	for _, leaf := range t.packageMap {
+		if leaf == nil || leaf.xtest {
+			continue
+		}
+		for _, edge := range leaf.deps {
+			if !edge.Test {
+				afferent[edge.To]++
+			}
+		}
+	}
+
+	metrics := []Metrics{}
+	for _, name := range t.sortedNames() {
+		leaf := t.packageMap[name]
+		if leaf == nil || leaf.xtest || leaf.IsBroken() {
+			continue
+		}
+
+		m := Metrics{
+			ImportPath:  name,
+			DisplayName: leaf.displayName,
+			Afferent:    afferent[name],
+		}
+		for _, edge := range leaf.deps {
+			if !edge.Test {
+				m.Efferent++
+			}
+		}
+		if m.Afferent+m.Efferent > 0 {
+			m.Instability = float64(m.Efferent) / float64(m.Afferent+m.Efferent)
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics
+}
+
+// sources are counts taken from a package's (non-test) Go files.
+type sources struct {
+	interfaces int
+	types      int
+	lines      int
+}
+
+// CountSources parses each of the receiver's packages' (non-test) Go files, and
+// remembers what Metrics and the LinesOfCode measure need from them. Otherwise
+// they're parsed when first needed, so call this first if the files may be
+// gone by then (e.g. if they're checked out into a temporary directory).
+func (t *Tree) CountSources() {
+	for _, leaf := range t.packageMap {
+		if leaf != nil {
+			leaf.countSources()
+		}
+	}
+}
+
+// countSources returns the counts taken from the receiver's package's files,
+// taking them first if need be.
+func (l *Leaf) countSources() *sources {
+	if l.sources == nil {
+		s := sources{}
+		if l.pkg != nil {
+			s.interfaces, s.types = countTypes(l.pkg)
+			s.lines = countLines(l.pkg)
+		}
+		l.sources = &s
+	}
+	return l.sources
+}
+
+// countTypes parses the package's (non-test) Go files, and counts the interface
+// types and all the named types they declare. Files that can't be parsed are
+// skipped.
+func countTypes(pkg *Package) (interfaces, types int) {
+	if pkg.Dir == "" {
+		return 0, 0
+	}
+
+	fset := token.NewFileSet()
+	for _, file := range pkg.GoFiles {
+		f, err := parser.ParseFile(fset, filepath.Join(pkg.Dir, file), nil, parser.SkipObjectResolution)
+		if err != nil {
+			logrus.Debugf("could not parse types of %s: %v", file, err)
+			continue
+		}
+		for _, decl := range f.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				types++
+				if _, ok := spec.(*ast.TypeSpec).Type.(*ast.InterfaceType); ok {
+					interfaces++
+				}
+			}
+		}
+	}
+	return interfaces, types
+}
@@ -0,0 +1,44 @@
+package tree
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	dir := t.TempDir()
+	src := "package b\n\ntype I interface{}\n\ntype S struct{}\n\ntype (\n\tT int\n\tU string\n)\n"
+	if err := os.WriteFile(filepath.Join(dir, "b.go"), []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	loader := NewFixtureLoader(
+		&Package{ImportPath: "ex.com/a", Imports: []string{"ex.com/b", "ex.com/c"}, TestImports: []string{"ex.com/d"}},
+		&Package{ImportPath: "ex.com/b", Dir: dir, GoFiles: []string{"b.go"}, Imports: []string{"ex.com/c"}, XTestImports: []string{"ex.com/a"}},
+		&Package{ImportPath: "ex.com/c"},
+		&Package{ImportPath: "ex.com/d"},
+	)
+	tr := NewTree("ex.com", loader)
+	tr.SetIncludeTests(true)
+	if _, err := tr.AddRecursive("a"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the counts outlive the files
+	tr.CountSources()
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Metrics{
+		{ImportPath: "ex.com/a", DisplayName: "a", Afferent: 0, Efferent: 2, Instability: 1, Distance: 0},
+		{ImportPath: "ex.com/b", DisplayName: "b", Afferent: 1, Efferent: 1, Instability: 0.5, Interfaces: 1, Types: 4, Abstractness: 0.25, Distance: 0.25},
+		{ImportPath: "ex.com/c", DisplayName: "c", Afferent: 2, Efferent: 0, Instability: 0, Distance: 1},
+		{ImportPath: "ex.com/d", DisplayName: "d", Afferent: 0, Efferent: 0, Instability: 0, Distance: 1},
+	}
+	if got := tr.Metrics(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+}
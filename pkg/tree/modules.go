@@ -0,0 +1,162 @@
+package tree
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/build"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ModuleFile returns the path to the go.mod file governing the given
+// directory, or an empty string if the directory is not inside a module.
+func ModuleFile(dir string) (string, error) {
+	cmd := exec.Command("go", "env", "GOMOD")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("could not run `go env GOMOD` in %s: %v", dir, err)
+	}
+	gomod := strings.TrimSpace(string(out))
+	if gomod == "" || gomod == "/dev/null" || gomod == "NUL" {
+		return "", nil
+	}
+	return gomod, nil
+}
+
+// listedPackage is the subset of `go list -json` output that the tree cares
+// about.
+type listedPackage struct {
+	Dir          string
+	ImportPath   string
+	Name         string
+	GoFiles      []string
+	TestGoFiles  []string
+	XTestGoFiles []string
+	Imports      []string
+	TestImports  []string
+	XTestImports []string
+	Error        *struct {
+		Err string
+	}
+}
+
+// ModuleLoader is a Loader that resolves packages by asking the go command
+// about them, from inside a module directory. Unlike build.Import with an empty
+// source directory, this understands go.mod, replace directives and the module
+// cache.
+type ModuleLoader struct {
+	dir      string
+	ctx      *build.Context
+	patterns []string
+	once     sync.Once
+	mu       sync.Mutex
+	cache    map[string]*listedPackage
+}
+
+// NewModuleLoader returns a new ModuleLoader that runs the go command in the
+// given directory. The build context's GOOS, GOARCH and BuildTags are passed
+// along to the go command; if ctx is nil, the go command's defaults are used.
+// Any patterns given (e.g. "example.com/mod/...") are listed,
+// along with their dependencies, the first time a package is loaded, so that
+// most later loads are answered from memory.
+func NewModuleLoader(dir string, ctx *build.Context, patterns ...string) *ModuleLoader {
+	return &ModuleLoader{
+		dir:      dir,
+		ctx:      ctx,
+		patterns: patterns,
+		cache:    make(map[string]*listedPackage),
+	}
+}
+
+// Load returns the package with the given import path.
+func (m *ModuleLoader) Load(importPath string) (*Package, error) {
+	m.once.Do(func() {
+		if len(m.patterns) > 0 {
+			args := append([]string{"-deps"}, m.patterns...)
+			if err := m.list(args...); err != nil {
+				logrus.Debugf("could not list %v: %v", m.patterns, err)
+			}
+		}
+	})
+
+	lp, ok := m.lookup(importPath)
+	if !ok {
+		if err := m.list(importPath); err != nil {
+			return nil, err
+		}
+		lp, ok = m.lookup(importPath)
+		if !ok {
+			return nil, fmt.Errorf("go list did not report package %s", importPath)
+		}
+	}
+
+	if lp.Error != nil {
+		return nil, fmt.Errorf("%s", lp.Error.Err)
+	}
+
+	pkg := &Package{
+		ImportPath:   lp.ImportPath,
+		Dir:          lp.Dir,
+		Name:         lp.Name,
+		GoFiles:      lp.GoFiles,
+		TestGoFiles:  lp.TestGoFiles,
+		XTestGoFiles: lp.XTestGoFiles,
+		Imports:      lp.Imports,
+		TestImports:  lp.TestImports,
+		XTestImports: lp.XTestImports,
+	}
+	readImportSpecs(pkg)
+	return pkg, nil
+}
+
+func (m *ModuleLoader) lookup(importPath string) (*listedPackage, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	lp, ok := m.cache[importPath]
+	return lp, ok
+}
+
+func (m *ModuleLoader) list(args ...string) error {
+	flags := []string{"list", "-e", "-json"}
+	env := os.Environ()
+	if m.ctx != nil {
+		if len(m.ctx.BuildTags) > 0 {
+			flags = append(flags, "-tags", strings.Join(m.ctx.BuildTags, ","))
+		}
+		env = append(env, "GOOS="+m.ctx.GOOS, "GOARCH="+m.ctx.GOARCH)
+	}
+	args = append(flags, args...)
+	logrus.Debugf("go %s (in %s)", strings.Join(args, " "), m.dir)
+
+	var stderr bytes.Buffer
+	cmd := exec.Command("go", args...)
+	cmd.Dir = filepath.Clean(m.dir)
+	cmd.Env = env
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("go list: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(out))
+	for {
+		var lp listedPackage
+		if err := dec.Decode(&lp); err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		m.mu.Lock()
+		m.cache[lp.ImportPath] = &lp
+		m.mu.Unlock()
+	}
+	return nil
+}
@@ -2,10 +2,11 @@ package tree
 
 import (
 	"fmt"
-	"go/build"
 	"path"
+	"runtime"
 	"sort"
 	"strings"
+	"sync"
 
 	"github.com/sirupsen/logrus"
 )
@@ -22,10 +23,9 @@ func (t *Tree) AddRecursive(name string) (bool, error) {
 }
 
 func (t *Tree) add(name string, recurse, root bool) (bool, error) {
-	logrus.Infof("Adding %s", name)
-
-	// skip the ones we should not include
-	if !t.shouldInclude(name) {
+	// skip the ones we should not include. Roots may be named relative to the
+	// parent directory, so they are filtered once their import path is known.
+	if name == "C" || !root && !t.shouldInclude(name) {
 		return false, nil
 	}
 
@@ -34,11 +34,137 @@ func (t *Tree) add(name string, recurse, root bool) (bool, error) {
 		return false, nil
 	}
 
-	pkg, iErr := t.importPkg(name)
+	leaf := t.loadLeaf(name, root)
+	if leaf == nil {
+		return false, nil
+	}
+
+	// roots may be named relative to the parent directory, but the package's
+	// importers will know it by its full import path
+	if leaf.pkg != nil && leaf.pkg.ImportPath != name {
+		name = leaf.pkg.ImportPath
+		if _, ok := t.packageMap[name]; ok {
+			return false, nil
+		}
+	}
+	if !t.shouldInclude(name) {
+		logrus.Infof("Skipping %s, which is filtered out", name)
+		return false, nil
+	}
+	level := t.plant(name, leaf)
+
+	if !recurse {
+		return true, nil
+	}
+
+	// go deeper, one level at a time. Each level is loaded concurrently, then
+	// merged into the package map in sorted order, so that the result doesn't
+	// depend on how the loads were scheduled.
+	skipped := make(map[string]bool)
+	for len(level) > 0 {
+		names := []string{}
+		for _, childPkg := range level {
+			if _, ok := t.packageMap[childPkg]; ok || skipped[childPkg] {
+				continue
+			}
+			if !t.shouldInclude(childPkg) || contains(names, childPkg) {
+				continue
+			}
+			names = append(names, childPkg)
+		}
+		sort.Strings(names)
+
+		next := []string{}
+		for i, childLeaf := range t.loadLeaves(names) {
+			if childLeaf == nil {
+				skipped[names[i]] = true
+				continue
+			}
+			next = append(next, t.plant(names[i], childLeaf)...)
+		}
+		level = next
+	}
+
+	return true, nil
+}
+
+// plant puts a newly-loaded leaf in the package map under the given name, along
+// with a leaf for its external test package if it has one (and the receiver
+// includes tests). It returns the imports of whatever it planted.
+func (t *Tree) plant(name string, leaf *Leaf) []string {
+	for i := range leaf.deps {
+		leaf.deps[i].From = name
+	}
+	t.packageMap[name] = leaf
+	deps := leaf.depNames()
+
+	if xtest := t.xtestLeaf(name+"_test", leaf); xtest != nil {
+		t.packageMap[name+"_test"] = xtest
+		deps = append(deps, xtest.depNames()...)
+	}
+	return deps
+}
+
+// xtestLeaf returns a leaf for the external test package (the "foo_test"
+// package in foo's directory) of the given leaf, or nil if there isn't one or
+// the receiver doesn't include tests.
+func (t *Tree) xtestLeaf(name string, leaf *Leaf) *Leaf {
+	if !t.includeTests || leaf.pkg == nil || len(leaf.pkg.XTestImports) == 0 {
+		return nil
+	}
+
+	xtest := NewLeaf(leaf.displayName + "_test")
+	xtest.pkg = leaf.pkg
+	xtest.xtest = true
+	xtest.deps = newEdges(name, leaf.pkg, nil, t.filterImports(leaf.pkg.XTestImports), leaf.pkg.XTestGoFiles)
+	return xtest
+}
+
+// loadLeaves loads the named packages with a pool of workers. The returned
+// slice is in the same order as the given names, with nil for each package that
+// should not be added to the tree.
+func (t *Tree) loadLeaves(names []string) []*Leaf {
+	leaves := make([]*Leaf, len(names))
+
+	workers := t.jobs
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers > len(names) {
+		workers = len(names)
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				leaves[i] = t.loadLeaf(names[i], false)
+			}
+		}()
+	}
+	for i := range names {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	return leaves
+}
+
+// loadLeaf builds a new leaf for the named package, or returns nil if the
+// package should not be added to the tree. It does not touch the package map,
+// so it is safe to call from several goroutines at once.
+func (t *Tree) loadLeaf(name string, root bool) *Leaf {
+	logrus.Infof("Adding %s", name)
+
+	pkg, iErr := t.importPkg(name, root)
 	if iErr != nil {
 		// we had trouble importing this, which means it's not a local package
 		if !t.includeExts {
-			return false, nil
+			return nil
 		}
 	}
 
@@ -49,13 +175,12 @@ func (t *Tree) add(name string, recurse, root bool) (bool, error) {
 		// doesn't have a pkg
 		leaf := NewLeaf(name)
 		leaf.SetRoot(root)
-		t.packageMap[name] = leaf
-		return true, nil
+		return leaf
 	}
 
 	// only keep the externals if that flag is true
 	if !t.includeExts && !strings.HasPrefix(pkg.ImportPath, t.parentDirectory) {
-		return false, nil
+		return nil
 	}
 
 	if typedErr, ok := iErr.(importError); ok {
@@ -68,8 +193,7 @@ func (t *Tree) add(name string, recurse, root bool) (bool, error) {
 
 	// we got past the external checks and still have an import error
 	if iErr != nil {
-		t.packageMap[name] = leaf
-		return true, nil
+		return leaf
 	}
 
 	displayName := strings.TrimPrefix(name, t.parentDirectory)
@@ -80,26 +204,15 @@ func (t *Tree) add(name string, recurse, root bool) (bool, error) {
 	leaf.pkg = pkg
 
 	// make the list of packages this leaf imports
-	deps := t.filterImports(pkg.Imports)
+	files := pkg.GoFiles
+	testDeps := []string{}
 	if t.includeTests {
-		deps = append(deps, t.filterImports(pkg.TestImports)...)
-	}
-	deps = unique(deps)
-	sort.Strings(deps)
-	leaf.deps = deps
-	t.packageMap[name] = leaf
-
-	// go one level deeper...
-	if recurse {
-		for _, childPkg := range deps {
-			added, err := t.add(childPkg, recurse, false)
-			if err != nil {
-				return added, err
-			}
-		}
+		files = append(append([]string{}, files...), pkg.TestGoFiles...)
+		testDeps = t.filterImports(pkg.TestImports)
 	}
+	leaf.deps = newEdges(name, pkg, t.filterImports(pkg.Imports), testDeps, files)
 
-	return true, nil
+	return leaf
 }
 
 func (t *Tree) filterImports(imports []string) []string {
@@ -124,69 +237,80 @@ func (t *Tree) shouldInclude(name string) bool {
 	if name == "C" {
 		return false
 	}
+	if t.filter != nil && !t.filter(name) {
+		return false
+	}
 
 	return true
 }
 
 type importError struct {
-	parentDirPkg *build.Package
 	parentDirErr error
-	gopathPkg    *build.Package
-	gopathErr    error
-	vendorPkg    *build.Package
-	vendorErr    error
+	nameErr      error
 }
 
 func (i importError) Error() string {
-	return fmt.Sprintf("{parent error: %v; gopath error: %v; vendor error: %v}", i.parentDirErr, i.gopathErr, i.vendorErr)
+	return fmt.Sprintf("{parent error: %v; error: %v}", i.parentDirErr, i.nameErr)
 }
 
 func (i importError) String() string {
-	s := fmt.Sprintf("parent dir package:\n%+v\n", i.parentDirPkg)
-	s += fmt.Sprintf("parent dir error: %v\n", i.parentDirErr)
-	s += fmt.Sprintf("gopath package:\n%+v\n", i.gopathPkg)
-	s += fmt.Sprintf("gopath error; %v\n", i.gopathErr)
-	s += fmt.Sprintf("vendor package:\n%+v\n", i.vendorPkg)
-	s += fmt.Sprintf("vendor error: %v", i.vendorErr)
+	s := fmt.Sprintf("parent dir error: %s\n", errorString(i.parentDirErr))
+	s += fmt.Sprintf("error: %s", errorString(i.nameErr))
 	return s
 }
 
-func (t *Tree) importPkg(name string) (*build.Package, error) {
-	// the given name may or may not have the parent directory prefixed
-	name = strings.TrimPrefix(name, t.parentDirectory)
-	parentName := path.Join(t.parentDirectory, name)
+// errorString prefers an error's String method (which may be more detailed)
+// over its Error method.
+func errorString(err error) string {
+	if s, ok := err.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return fmt.Sprintf("%v", err)
+}
+
+// importPkg loads the named package. Roots are named by the user, and may or
+// may not have the parent directory prefixed. Everything else comes from an
+// import list, and so is already a full import path.
+func (t *Tree) importPkg(name string, root bool) (*Package, error) {
+	if !root {
+		return t.loader.Load(name)
+	}
+
+	parentName := path.Join(t.parentDirectory, strings.TrimPrefix(name, t.parentDirectory))
 	iErr := importError{}
 
 	// first try the name prefixed with the parent directory
-	pPkg, pErr := build.Import(parentName, "", 0)
+	pPkg, pErr := t.loader.Load(parentName)
 	if pErr == nil {
 		return pPkg, nil
 	}
 	iErr.parentDirErr = pErr
-	iErr.parentDirPkg = pPkg
 
-	// then try the package without a source dir
-	gPkg, gErr := build.Import(name, "", 0)
-	if gErr == nil {
-		return gPkg, nil
+	// then try the name as given
+	pkg, err := t.loader.Load(name)
+	if err == nil {
+		return pkg, nil
 	}
-	iErr.gopathErr = gErr
-	iErr.gopathPkg = gPkg
-
-	// try importing it from vendor...
-	name = path.Join("vendor", name)
-	vPkg, vErr := build.Import(name, "", 0)
-	if vErr == nil {
-		return vPkg, nil
-	}
-	iErr.vendorErr = vErr
-	iErr.vendorPkg = vPkg
+	iErr.nameErr = err
 
 	return nil, iErr
 }
 
+// resolve returns the name the receiver knows a package by. Packages may be
+// named with or without the parent directory prefix.
+func (t *Tree) resolve(name string) string {
+	if _, ok := t.packageMap[name]; ok {
+		return name
+	}
+	parentName := path.Join(t.parentDirectory, name)
+	if _, ok := t.packageMap[parentName]; ok {
+		return parentName
+	}
+	return name
+}
+
 func unique(st []string) []string {
-	var r []string
+	r := []string{}
 	for _, s := range st {
 		if !contains(r, s) {
 			r = append(r, s)
@@ -209,6 +333,7 @@ func contains(st []string, s string) bool {
 // "grow" operations)
 func (t *Tree) Keep(name string) error {
 	logrus.Infof("Keeping %s", name)
+	name = t.resolve(name)
 	leaf, ok := t.packageMap[name]
 	if !ok {
 		return fmt.Errorf("package %s not found", name)
@@ -236,9 +361,9 @@ func (t *Tree) Grow(count int) {
 	// grow down
 	for _, leaf := range t.packageMap {
 		if leaf.keep {
-			for _, importName := range leaf.deps {
-				if _, ok := copy[importName]; ok {
-					copy[importName].keep = true
+			for _, edge := range leaf.deps {
+				if _, ok := copy[edge.To]; ok {
+					copy[edge.To].keep = true
 				}
 			}
 		}
@@ -246,8 +371,8 @@ func (t *Tree) Grow(count int) {
 
 	// grow up
 	for name, leaf := range t.packageMap {
-		for _, importName := range leaf.deps {
-			if upLeaf, ok := t.packageMap[importName]; ok {
+		for _, edge := range leaf.deps {
+			if upLeaf, ok := t.packageMap[edge.To]; ok {
 				if upLeaf.keep {
 					copy[name].keep = true
 				}
@@ -292,10 +417,10 @@ func (t *Tree) Prune() {
 			delete(t.packageMap, name)
 			continue
 		}
-		newDeps := []string{}
-		for _, importName := range leaf.deps {
-			if importLeaf, ok := t.packageMap[importName]; ok && importLeaf.keep {
-				newDeps = append(newDeps, importName)
+		newDeps := []Edge{}
+		for _, edge := range leaf.deps {
+			if importLeaf, ok := t.packageMap[edge.To]; ok && importLeaf.keep {
+				newDeps = append(newDeps, edge)
 			}
 			leaf.deps = newDeps
 			t.packageMap[name] = leaf
@@ -305,6 +430,7 @@ func (t *Tree) Prune() {
 
 // Branch marks all packages between the given package and the root for keeping.
 func (t *Tree) Branch(b string) error {
+	b = t.resolve(b)
 	for name, leaf := range t.packageMap {
 		if leaf.IsRoot() {
 			if err := t.branchBetween(name, b); err != nil {
@@ -323,21 +449,22 @@ func (t *Tree) branchBetween(upper, lower string) error {
 
 	broad := t.Broaden()
 	inverse := map[string][]string{}
-	for _, m := range broad {
-		for k, v := range m {
-			if _, ok := inverse[v]; !ok {
-				inverse[v] = []string{}
-			}
-			inverse[v] = append(inverse[v], k)
-		}
+	for _, edge := range broad {
+		inverse[edge.To] = append(inverse[edge.To], edge.From)
 	}
 
 	check := []string{lower}
+	checked := map[string]bool{lower: true}
 
 	for len(check) > 0 {
 		this := check[0]
 		check = check[1:]
 		for _, importer := range inverse[this] {
+			// import cycles would otherwise have us going round forever
+			if checked[importer] {
+				continue
+			}
+			checked[importer] = true
 			l := t.packageMap[importer]
 			l.keep = true
 			t.packageMap[importer] = l
@@ -0,0 +1,234 @@
+package tree
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// fixtureTree returns a tree of the given packages, rooted at ex.com/a. Each
+// package is named relative to ex.com, and imports the packages it maps to.
+func fixtureTree(t *testing.T, imports map[string][]string) *Tree {
+	t.Helper()
+	tr := NewTree("ex.com", fixtureLoader(imports))
+	if _, err := tr.AddRecursive("a"); err != nil {
+		t.Fatal(err)
+	}
+	return tr
+}
+
+// fixtureLoader returns a loader of the given packages (see fixtureTree).
+func fixtureLoader(imports map[string][]string) *FixtureLoader {
+	loader := NewFixtureLoader()
+	for name, deps := range imports {
+		pkg := &Package{ImportPath: "ex.com/" + name, Name: name, GoFiles: []string{name + ".go"}}
+		for _, dep := range deps {
+			pkg.Imports = append(pkg.Imports, "ex.com/"+dep)
+		}
+		loader.Add(pkg)
+	}
+	return loader
+}
+
+// diamond is a -> b -> d -> e and a -> c -> d.
+var diamond = map[string][]string{
+	"a": {"b", "c"},
+	"b": {"d"},
+	"c": {"d"},
+	"d": {"e"},
+	"e": nil,
+}
+
+func TestKeepGrowPrune(t *testing.T) {
+	tests := []struct {
+		name  string
+		keeps []string
+		grow  int
+		want  []string
+	}{
+		{"keep only", []string{"d"}, 0, []string{"ex.com/d"}},
+		{"grow both ways", []string{"d"}, 1, []string{"ex.com/b", "ex.com/c", "ex.com/d", "ex.com/e"}},
+		{"grow twice", []string{"e"}, 2, []string{"ex.com/b", "ex.com/c", "ex.com/d", "ex.com/e"}},
+		{"grow from root", []string{"a"}, 1, []string{"ex.com/a", "ex.com/b", "ex.com/c"}},
+		{"full name", []string{"ex.com/b"}, 0, []string{"ex.com/b"}},
+		{"several keeps", []string{"b", "e"}, 0, []string{"ex.com/b", "ex.com/e"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := fixtureTree(t, diamond)
+			for _, keep := range tt.keeps {
+				if err := tr.Keep(keep); err != nil {
+					t.Fatal(err)
+				}
+			}
+			tr.Grow(tt.grow)
+			tr.Prune()
+			if got := tr.PackageNames(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeepMissing(t *testing.T) {
+	tr := fixtureTree(t, diamond)
+	if err := tr.Keep("z"); err == nil {
+		t.Error("expected an error keeping a missing package")
+	}
+}
+
+func TestBranch(t *testing.T) {
+	tests := []struct {
+		name   string
+		branch string
+		want   []string
+	}{
+		{"leaf", "e", []string{"ex.com/a", "ex.com/b", "ex.com/c", "ex.com/d", "ex.com/e"}},
+		{"one side", "c", []string{"ex.com/a", "ex.com/c"}},
+		{"root", "a", []string{"ex.com/a"}},
+		{"full name", "ex.com/b", []string{"ex.com/a", "ex.com/b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := fixtureTree(t, diamond)
+			if err := tr.Branch(tt.branch); err != nil {
+				t.Fatal(err)
+			}
+			tr.Prune()
+			if got := tr.PackageNames(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBranchMissing(t *testing.T) {
+	tr := fixtureTree(t, diamond)
+	if err := tr.Branch("z"); err == nil {
+		t.Error("expected an error branching to a missing package")
+	}
+}
+
+func TestBranchCycle(t *testing.T) {
+	tr := fixtureTree(t, map[string][]string{
+		"a": {"b"},
+		"b": {"c"},
+		"c": {"b", "d"},
+		"d": nil,
+	})
+	if err := tr.Branch("d"); err != nil {
+		t.Fatal(err)
+	}
+	tr.Prune()
+	want := []string{"ex.com/a", "ex.com/b", "ex.com/c", "ex.com/d"}
+	if got := tr.PackageNames(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestJobs(t *testing.T) {
+	// a wide tree, so that each level has plenty of packages to load at once
+	imports := map[string][]string{"a": nil}
+	for i := 0; i < 20; i++ {
+		mid := fmt.Sprintf("m%02d", i)
+		imports["a"] = append(imports["a"], mid)
+		for j := 0; j < 5; j++ {
+			imports[mid] = append(imports[mid], fmt.Sprintf("l%02d", (i+j)%20))
+		}
+	}
+	for i := 0; i < 20; i++ {
+		imports[fmt.Sprintf("l%02d", i)] = []string{fmt.Sprintf("l%02d", (i+1)%20)}
+	}
+
+	load := func(jobs int) string {
+		tr := NewTree("ex.com", fixtureLoader(imports))
+		tr.SetJobs(jobs)
+		if _, err := tr.AddRecursive("a"); err != nil {
+			t.Fatal(err)
+		}
+		s, err := tr.JSON()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	want := load(1)
+	for _, jobs := range []int{2, 8, 0} {
+		if got := load(jobs); got != want {
+			t.Errorf("tree loaded with %d jobs differs from the one loaded with 1:\n%s\nwant:\n%s", jobs, got, want)
+		}
+	}
+}
+
+func TestTests(t *testing.T) {
+	// a's own test files import b (which a imports anyway) and c, and its
+	// external test package imports a and d
+	loader := NewFixtureLoader(
+		&Package{
+			ImportPath:   "ex.com/a",
+			Name:         "a",
+			GoFiles:      []string{"a.go"},
+			TestGoFiles:  []string{"a_internal_test.go"},
+			XTestGoFiles: []string{"a_test.go"},
+			Imports:      []string{"ex.com/b"},
+			TestImports:  []string{"ex.com/b", "ex.com/c"},
+			XTestImports: []string{"ex.com/a", "ex.com/d"},
+		},
+		&Package{ImportPath: "ex.com/b"},
+		&Package{ImportPath: "ex.com/c"},
+		&Package{ImportPath: "ex.com/d"},
+	)
+	type edge struct {
+		from, to string
+		test     bool
+	}
+	tests := []struct {
+		tests     bool
+		wantNames []string
+		wantEdges []edge
+	}{
+		{
+			tests:     false,
+			wantNames: []string{"ex.com/a", "ex.com/b"},
+			wantEdges: []edge{{"ex.com/a", "ex.com/b", false}},
+		},
+		{
+			tests:     true,
+			wantNames: []string{"ex.com/a", "ex.com/a_test", "ex.com/b", "ex.com/c", "ex.com/d"},
+			wantEdges: []edge{
+				{"ex.com/a", "ex.com/b", false},
+				{"ex.com/a", "ex.com/c", true},
+				{"ex.com/a_test", "ex.com/a", true},
+				{"ex.com/a_test", "ex.com/d", true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		tr := NewTree("ex.com", loader)
+		tr.SetIncludeTests(tt.tests)
+		if _, err := tr.AddRecursive("a"); err != nil {
+			t.Fatal(err)
+		}
+		if got := tr.PackageNames(); !reflect.DeepEqual(got, tt.wantNames) {
+			t.Errorf("tests %v: got packages %v, want %v", tt.tests, got, tt.wantNames)
+		}
+		edges := []edge{}
+		for _, e := range tr.Broaden() {
+			edges = append(edges, edge{e.From, e.To, e.Test})
+		}
+		if !reflect.DeepEqual(edges, tt.wantEdges) {
+			t.Errorf("tests %v: got edges %v, want %v", tt.tests, edges, tt.wantEdges)
+		}
+		if !tt.tests {
+			continue
+		}
+		xtest := tr.packageMap["ex.com/a_test"]
+		if !xtest.IsXTest() || tr.packageMap["ex.com/a"].IsXTest() {
+			t.Error("only a_test should be an external test package")
+		}
+		if got := xtest.DisplayName(); got != "a_test" {
+			t.Errorf("got xtest display name %q, want a_test", got)
+		}
+	}
+}
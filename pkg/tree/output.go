@@ -4,28 +4,37 @@ import (
 	"fmt"
 	"path"
 	"sort"
+	"strings"
 
 	"github.com/awalterschulze/gographviz"
 	"github.com/sirupsen/logrus"
 )
 
-// Broaden returns a list of maps. Each map has one key, and that key and value
-// each represent a package name. Each map represents "this package imports
-// that package".
-func (t *Tree) Broaden() []map[string]string {
-	b := make([]map[string]string, 0, len(t.packageMap))
-	for name, leaf := range t.packageMap {
+// Broaden returns a list of all the receiver's edges. Each edge represents
+// "this package imports that package". The list is sorted by importer, then by
+// import.
+func (t *Tree) Broaden() []Edge {
+	b := make([]Edge, 0, len(t.packageMap))
+	for _, name := range t.sortedNames() {
+		leaf := t.packageMap[name]
 		if leaf == nil {
 			continue
 		}
-		for _, importName := range leaf.deps {
-			m := map[string]string{name: importName}
-			b = append(b, m)
-		}
+		b = append(b, leaf.deps...)
 	}
 	return b
 }
 
+// sortedNames returns the keys of the receiver's package map, sorted.
+func (t *Tree) sortedNames() []string {
+	names := make([]string, 0, len(t.packageMap))
+	for name := range t.packageMap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 // PackageNames returns a sorted list of all of the tree's packages
 func (t *Tree) PackageNames() []string {
 	names := make(map[string]bool)
@@ -34,8 +43,8 @@ func (t *Tree) PackageNames() []string {
 			continue
 		}
 		names[name] = true
-		for _, importName := range leaf.deps {
-			names[importName] = true
+		for _, edge := range leaf.deps {
+			names[edge.To] = true
 		}
 	}
 	r := make([]string, 0, len(names))
@@ -51,8 +60,8 @@ func (t *Tree) String() string {
 	t.countImports()
 
 	r := "Tree{\n"
-	for name, leaf := range t.packageMap {
-		r += fmt.Sprintf("\t%s: %s\n", name, leaf)
+	for _, name := range t.sortedNames() {
+		r += fmt.Sprintf("\t%s: %s\n", name, t.packageMap[name])
 	}
 	r += "}\n"
 	return r
@@ -95,11 +104,7 @@ func (t *Tree) Graphviz() (string, error) {
 
 	t.countImports()
 
-	packageNames := t.PackageNames()
-	names := make(map[string]string)
-	for i, packageName := range packageNames {
-		names[packageName] = fmt.Sprintf("N%d", i)
-	}
+	names := t.nodeNames()
 
 	logrus.Debugf("package names: %v", names)
 
@@ -117,10 +122,25 @@ func (t *Tree) Graphviz() (string, error) {
 		return "", err
 	}
 
+	// group the nodes by directory
+	graphs := make(map[string]string)
+	if t.cluster {
+		var err error
+		graphs, err = t.addClusters(g, topGraphName, t.PackageNames())
+		if err != nil {
+			return "", err
+		}
+	}
+
+	measureAttrs := t.measureAttributes()
 	nodesAdded := []string{}
 
 	// add package nodes
 	for packageName, nodeName := range names {
+		graph, ok := graphs[packageName]
+		if !ok {
+			graph = topGraphName
+		}
 		leaf, ok := t.packageMap[packageName]
 		if !ok {
 			packageName = path.Join("vendor", packageName)
@@ -133,31 +153,43 @@ func (t *Tree) Graphviz() (string, error) {
 		if leaf == nil {
 			continue
 		}
-		if err := g.AddNode(topGraphName, nodeName, leaf.attributes()); err != nil {
+		attrs := leaf.attributes()
+		for k, v := range measureAttrs[packageName] {
+			attrs[k] = v
+		}
+		if err := g.AddNode(graph, nodeName, attrs); err != nil {
 			return "", err
 		}
 		nodesAdded = append(nodesAdded, nodeName)
 	}
 
-	// add import edges
+	// add import edges, highlighting the ones that are part of a cycle
+	cycleMembers := t.cycleMembers()
 	for _, edge := range edges {
-		for left, right := range edge {
-			if contains(nodesAdded, names[left]) && contains(nodesAdded, names[right]) {
-				nodeLeft := names[left]
-				nodeRight := names[right]
-				if err := g.AddEdge(nodeLeft, nodeRight, true, map[string]string{
-					"weight": "1",
-				}); err != nil {
-					return "", err
-				}
+		if contains(nodesAdded, names[edge.From]) && contains(nodesAdded, names[edge.To]) {
+			nodeLeft := names[edge.From]
+			nodeRight := names[edge.To]
+			attrs := t.edgeAttributes(edge)
+			fromSCC, fromOK := cycleMembers[edge.From]
+			toSCC, toOK := cycleMembers[edge.To]
+			if fromOK && toOK && fromSCC == toSCC {
+				attrs["color"] = fmt.Sprintf("\"%s\"", CycleColor)
+			}
+			for k, v := range t.packageMap[edge.From].depAttrs[edge.To] {
+				attrs[k] = v
+			}
+			if err := g.AddEdge(nodeLeft, nodeRight, true, attrs); err != nil {
+				return "", err
 			}
 		}
 	}
 
 	// add Legend
-	// if err := addLegend(g); err != nil {
-	// 	return "", err
-	// }
+	if t.legend {
+		if err := addLegend(g, topGraphName, t.colorBy); err != nil {
+			return "", err
+		}
+	}
 
 	ast, err := g.WriteAst()
 	if err != nil {
@@ -167,6 +199,55 @@ func (t *Tree) Graphviz() (string, error) {
 	return ast.String(), nil
 }
 
+// edgeAttributes returns the graphviz attributes for an edge. Test imports are
+// dashed, blank and dot imports get their own arrowheads, and the files making
+// the import are listed in the tooltip. If the symbols the import uses are
+// known, they're listed in the tooltip too, and weigh the edge.
+func (t *Tree) edgeAttributes(edge Edge) map[string]string {
+	attr := map[string]string{
+		"weight": "1",
+	}
+	if uses := symbolUses(edge); uses > 0 {
+		attr["weight"] = fmt.Sprintf("%d", uses)
+	}
+	if edge.Test {
+		attr["style"] = "dashed"
+	}
+	if edge.Blank {
+		attr["arrowhead"] = "odot"
+	}
+	if edge.Dot {
+		attr["arrowhead"] = "dot"
+	}
+	if len(edge.Files) > 0 {
+		tooltip := strings.Join(edge.Files, "\\n")
+		if len(edge.Constraints) > 0 {
+			tooltip += "\\n//go:build " + strings.Join(edge.Constraints, "\\n//go:build ")
+		}
+		for _, s := range edge.Symbols {
+			tooltip += fmt.Sprintf("\\n%s %d", s.Name, s.Uses)
+		}
+		for _, via := range edge.Via {
+			tooltip += "\\n" + via
+		}
+		attr["tooltip"] = fmt.Sprintf("\"%s\"", tooltip)
+	}
+	if label := t.edgeLabel(edge); label != "" {
+		attr["label"] = fmt.Sprintf("\"%s\"", label)
+	}
+	return attr
+}
+
+// nodeNames returns a short, stable node name for each of the receiver's
+// packages.
+func (t *Tree) nodeNames() map[string]string {
+	names := make(map[string]string)
+	for i, packageName := range t.PackageNames() {
+		names[packageName] = fmt.Sprintf("N%d", i)
+	}
+	return names
+}
+
 func (t *Tree) countImports() {
 	// reset import counts
 	for _, leaf := range t.packageMap {
@@ -180,11 +261,11 @@ func (t *Tree) countImports() {
 		if leaf == nil {
 			continue
 		}
-		for _, importName := range leaf.deps {
-			importLeaf, ok := t.packageMap[importName]
+		for _, edge := range leaf.deps {
+			importLeaf, ok := t.packageMap[edge.To]
 			if ok && importLeaf != nil {
 				importLeaf.importCount++
-				t.packageMap[importName] = importLeaf
+				t.packageMap[edge.To] = importLeaf
 			}
 		}
 	}
@@ -195,3 +276,103 @@ type legend struct {
 	fillcolor string
 	doc       string
 }
+
+// the colors a node may be striped with
+var nodeLegends = []legend{
+	{key: "root", fillcolor: RootColor, doc: "one of the named root packages"},
+	{key: "keep", fillcolor: UserKeepColor, doc: "named with --keep or --branch"},
+	{key: "single", fillcolor: SingleParentColor, doc: "imported by exactly one package"},
+	{key: "broken", fillcolor: BrokenColor, doc: "could not be loaded"},
+}
+
+// the ways an edge may be drawn
+var edgeLegends = []struct {
+	key   string
+	doc   string
+	attrs map[string]string
+}{
+	{key: "import", doc: "imports", attrs: map[string]string{}},
+	{key: "test", doc: "imports only in tests", attrs: map[string]string{"style": "dashed"}},
+	{key: "blank", doc: "imports as _", attrs: map[string]string{"arrowhead": "odot"}},
+	{key: "dot", doc: "imports as .", attrs: map[string]string{"arrowhead": "dot"}},
+	{key: "cycle", doc: "imports within a cycle", attrs: map[string]string{"color": fmt.Sprintf("\"%s\"", CycleColor)}},
+	{key: "platforms", doc: "imports on the labelled\\nplatforms only", attrs: map[string]string{"label": "\"os/arch\""}},
+	{key: "count", doc: "N imports between the\\npackages of collapsed nodes", attrs: map[string]string{"label": "\"N imports\""}},
+}
+
+// addLegend adds a cluster to the graph explaining the colors, shapes and
+// labels the other nodes and edges use. If the nodes are colored by a measure,
+// it explains that scale instead of the usual node colors.
+func addLegend(g *gographviz.Graph, parentGraph string, colorBy string) error {
+	const cluster = "cluster_legend"
+	if err := g.AddSubGraph(parentGraph, cluster, map[string]string{
+		"label": "Legend",
+		"style": "dashed",
+	}); err != nil {
+		return err
+	}
+
+	entries := []struct {
+		name  string
+		attrs map[string]string
+	}{
+		{"legend_label", map[string]string{
+			"label": "\"package\\nN up M down\\n\\nimported by N packages,\\nimports M packages\\n\\nstriped with every\\ncolor that applies\"",
+			"shape": "box",
+		}},
+		{"legend_xtest", map[string]string{
+			"label": "\"package_test\\n\\nan external test package\"",
+			"shape": "box",
+			"style": "dashed",
+		}},
+	}
+	legends := nodeLegends
+	if colorBy != "" {
+		low, high := HeatColors[0], HeatColors[len(HeatColors)-1]
+		if colorBy == Coverage {
+			low, high = high, low
+		}
+		legends = []legend{
+			{key: "low", fillcolor: low, doc: "lowest " + colorBy},
+			{key: "high", fillcolor: high, doc: "highest " + colorBy},
+			{key: "unmeasured", fillcolor: UnmeasuredColor, doc: "no " + colorBy + " known"},
+		}
+	}
+	for _, l := range legends {
+		entries = append(entries, struct {
+			name  string
+			attrs map[string]string
+		}{"legend_" + l.key, map[string]string{
+			"label":     fmt.Sprintf("\"%s\"", l.doc),
+			"shape":     "box",
+			"style":     "filled",
+			"fillcolor": fmt.Sprintf("\"%s\"", l.fillcolor),
+		}})
+	}
+	for _, e := range entries {
+		if err := g.AddNode(cluster, e.name, e.attrs); err != nil {
+			return err
+		}
+	}
+
+	for _, l := range edgeLegends {
+		from := "legend_" + l.key + "_from"
+		to := "legend_" + l.key + "_to"
+		if err := g.AddNode(cluster, from, map[string]string{
+			"label": fmt.Sprintf("\"%s\"", l.doc),
+			"shape": "plaintext",
+		}); err != nil {
+			return err
+		}
+		if err := g.AddNode(cluster, to, map[string]string{
+			"label": "\"\"",
+			"shape": "point",
+		}); err != nil {
+			return err
+		}
+		if err := g.AddEdge(from, to, true, l.attrs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package tree
+
+import (
+	"fmt"
+)
+
+// ShortestPath returns the shortest chain of imports from one package to
+// another, including both. If there are several, it returns the one that comes
+// first alphabetically, hop by hop.
+func (t *Tree) ShortestPath(from, to string) ([]string, error) {
+	from, to, err := t.resolvePair(from, to)
+	if err != nil {
+		return nil, err
+	}
+	p := t.shortestPath(from, to, func(string) bool { return true })
+	if p == nil {
+		return nil, fmt.Errorf("%s does not import %s", from, to)
+	}
+	return p, nil
+}
+
+// MaxPaths is the most chains AllPaths returns. The number of chains between
+// two packages can grow exponentially with the size of the tree.
+const MaxPaths = 1000
+
+// AllPaths returns up to limit chains of imports from one package to another,
+// shortest first, with ties broken alphabetically. Each chain includes both
+// ends and visits each package at most once. A limit of zero or less, or more
+// than MaxPaths, means MaxPaths.
+//
+// The chains are found one length at a time, depth first, and the search stops
+// as soon as it has limit of them, so it never holds more than one partial
+// chain at once.
+func (t *Tree) AllPaths(from, to string, limit int) ([][]string, error) {
+	from, to, err := t.resolvePair(from, to)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 || limit > MaxPaths {
+		limit = MaxPaths
+	}
+
+	dist := t.distancesTo(to)
+	paths := [][]string{}
+	if shortest, ok := dist[from]; ok {
+		// a chain can't be longer than the number of packages that reach the
+		// destination
+		for hops := shortest; hops < len(dist) && len(paths) < limit; hops++ {
+			paths = t.walkPaths([]string{from}, to, hops, dist, limit, paths)
+		}
+	}
+
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("%s does not import %s", from, to)
+	}
+	return paths, nil
+}
+
+// walkPaths appends to paths each chain that extends p to the destination in
+// exactly the given number of imports, in alphabetical order, until there are
+// limit of them. It only steps towards packages whose distance to the
+// destination leaves room to get there in time.
+func (t *Tree) walkPaths(p []string, to string, hops int, dist map[string]int, limit int, paths [][]string) [][]string {
+	last := p[len(p)-1]
+	if last == to {
+		if hops == 0 {
+			paths = append(paths, append([]string{}, p...))
+		}
+		return paths
+	}
+	for _, edge := range t.packageMap[last].deps {
+		if len(paths) >= limit {
+			break
+		}
+		if d, ok := dist[edge.To]; !ok || d >= hops || contains(p, edge.To) {
+			continue
+		}
+		paths = t.walkPaths(append(p, edge.To), to, hops-1, dist, limit, paths)
+	}
+	return paths
+}
+
+// resolvePair resolves two package names, making sure the receiver has both.
+func (t *Tree) resolvePair(from, to string) (string, string, error) {
+	from = t.resolve(from)
+	to = t.resolve(to)
+	for _, name := range []string{from, to} {
+		if leaf, ok := t.packageMap[name]; !ok || leaf == nil {
+			return "", "", fmt.Errorf("package %s not found", name)
+		}
+	}
+	return from, to, nil
+}
+
+// distancesTo returns the fewest imports it takes each package that imports
+// the named one, directly or not, to get there. The package itself is at zero.
+func (t *Tree) distancesTo(name string) map[string]int {
+	inverse := map[string][]string{}
+	for _, edge := range t.Broaden() {
+		inverse[edge.To] = append(inverse[edge.To], edge.From)
+	}
+
+	dist := map[string]int{name: 0}
+	check := []string{name}
+	for len(check) > 0 {
+		this := check[0]
+		check = check[1:]
+		for _, importer := range inverse[this] {
+			if _, ok := dist[importer]; !ok {
+				dist[importer] = dist[this] + 1
+				check = append(check, importer)
+			}
+		}
+	}
+	return dist
+}
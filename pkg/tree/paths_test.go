@@ -0,0 +1,139 @@
+package tree
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestShortestPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to string
+		want     []string
+		wantErr  bool
+	}{
+		{"alphabetical tie", "a", "e", []string{"ex.com/a", "ex.com/b", "ex.com/d", "ex.com/e"}, false},
+		{"direct", "c", "d", []string{"ex.com/c", "ex.com/d"}, false},
+		{"itself", "d", "d", []string{"ex.com/d"}, false},
+		{"full names", "ex.com/a", "ex.com/c", []string{"ex.com/a", "ex.com/c"}, false},
+		{"wrong way", "e", "a", nil, true},
+		{"missing", "a", "z", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := fixtureTree(t, diamond)
+			got, err := tr.ShortestPath(tt.from, tt.to)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAllPaths(t *testing.T) {
+	withCycle := map[string][]string{
+		"a": {"b", "e"},
+		"b": {"c"},
+		"c": {"b", "d"},
+		"d": nil,
+		"e": {"d"},
+	}
+	tests := []struct {
+		name     string
+		imports  map[string][]string
+		from, to string
+		limit    int
+		want     [][]string
+		wantErr  bool
+	}{
+		{
+			name:    "all",
+			imports: diamond, from: "a", to: "e",
+			want: [][]string{
+				{"ex.com/a", "ex.com/b", "ex.com/d", "ex.com/e"},
+				{"ex.com/a", "ex.com/c", "ex.com/d", "ex.com/e"},
+			},
+		},
+		{
+			name:    "limited",
+			imports: diamond, from: "a", to: "e", limit: 1,
+			want: [][]string{
+				{"ex.com/a", "ex.com/b", "ex.com/d", "ex.com/e"},
+			},
+		},
+		{
+			name:    "shortest first",
+			imports: withCycle, from: "a", to: "d",
+			want: [][]string{
+				{"ex.com/a", "ex.com/e", "ex.com/d"},
+				{"ex.com/a", "ex.com/b", "ex.com/c", "ex.com/d"},
+			},
+		},
+		{
+			name:    "wrong way",
+			imports: diamond, from: "e", to: "a",
+			wantErr: true,
+		},
+		{
+			name:    "missing",
+			imports: diamond, from: "z", to: "a",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := fixtureTree(t, tt.imports)
+			got, err := tr.AllPaths(tt.from, tt.to, tt.limit)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAllPathsMax(t *testing.T) {
+	// a chain of 24 diamonds has 2^24 paths from end to end, far too many to
+	// find them all
+	imports := map[string][]string{}
+	prev := "a"
+	for i := 0; i < 24; i++ {
+		left, right, next := fmt.Sprintf("l%02d", i), fmt.Sprintf("r%02d", i), fmt.Sprintf("n%02d", i)
+		imports[prev] = []string{left, right}
+		imports[left] = []string{next}
+		imports[right] = []string{next}
+		prev = next
+	}
+	imports[prev] = nil
+	tr := fixtureTree(t, imports)
+
+	for _, limit := range []int{0, -1, MaxPaths + 1} {
+		paths, err := tr.AllPaths("a", prev, limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(paths) != MaxPaths {
+			t.Errorf("limit %d: got %d paths, want %d", limit, len(paths), MaxPaths)
+		}
+	}
+
+	// the first chains go left as long as they can
+	paths, err := tr.AllPaths("a", prev, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, p := range paths {
+		if got, want := p[len(p)-2], fmt.Sprintf("ex.com/%s23", []string{"l", "r"}[i]); got != want {
+			t.Errorf("chain %d: got %s before the last diamond's end, want %s", i, got, want)
+		}
+		if got, want := p[1], "ex.com/l00"; got != want {
+			t.Errorf("chain %d: got %s after the start, want %s", i, got, want)
+		}
+	}
+}
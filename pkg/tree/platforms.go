@@ -0,0 +1,96 @@
+package tree
+
+import (
+	"sort"
+	"strings"
+)
+
+// Union returns a new tree holding every package and import from the given
+// trees, which are keyed by the platform (e.g. "linux/amd64") each was loaded
+// for. Each import in the new tree is annotated with the platforms whose tree
+// has it. The new tree takes its settings from the first tree, by platform.
+func Union(trees map[string]*Tree) *Tree {
+	platforms := make([]string, 0, len(trees))
+	for platform := range trees {
+		platforms = append(platforms, platform)
+	}
+	sort.Strings(platforms)
+
+	if len(platforms) == 0 {
+		return NewTree("", nil)
+	}
+
+	first := trees[platforms[0]]
+	u := NewTree(first.parentDirectory, first.loader)
+	u.includeTests = first.includeTests
+	u.includeExts = first.includeExts
+	u.jobs = first.jobs
+	u.platforms = platforms
+
+	for _, platform := range platforms {
+		for _, name := range trees[platform].sortedNames() {
+			leaf := trees[platform].packageMap[name]
+			uLeaf, ok := u.packageMap[name]
+			if !ok {
+				uLeaf = leaf.copy()
+				uLeaf.deps = []Edge{}
+				u.packageMap[name] = uLeaf
+			}
+			if leaf.root {
+				uLeaf.root = true
+			}
+			if uLeaf.pkg == nil {
+				uLeaf.pkg = leaf.pkg
+			}
+			for _, edge := range leaf.deps {
+				uLeaf.mergeEdge(edge, platform)
+			}
+		}
+	}
+
+	for _, leaf := range u.packageMap {
+		sort.Slice(leaf.deps, func(i, j int) bool {
+			return leaf.deps[i].To < leaf.deps[j].To
+		})
+	}
+
+	return u
+}
+
+// mergeEdge adds an edge from the given platform's tree to the receiver, which
+// is part of a union tree.
+func (l *Leaf) mergeEdge(edge Edge, platform string) {
+	for i := range l.deps {
+		e := &l.deps[i]
+		if e.To != edge.To {
+			continue
+		}
+		// a production import on any platform is a production import
+		e.Test = e.Test && edge.Test
+		e.Blank = e.Blank || edge.Blank
+		e.Dot = e.Dot || edge.Dot
+		e.Files = unique(append(append([]string{}, e.Files...), edge.Files...))
+		sort.Strings(e.Files)
+		e.Constraints = unique(append(append([]string{}, e.Constraints...), edge.Constraints...))
+		sort.Strings(e.Constraints)
+		e.Platforms = append(e.Platforms, platform)
+		return
+	}
+	edge.Platforms = []string{platform}
+	l.deps = append(l.deps, edge)
+}
+
+// Platforms returns the platforms the receiver was loaded for, if it is the
+// union of several trees.
+func (t *Tree) Platforms() []string {
+	return t.platforms
+}
+
+// platformLabel describes the platforms an import exists on, or returns an
+// empty string if it exists on all of them.
+func (t *Tree) platformLabel(edge Edge) string {
+	if len(edge.Platforms) == 0 || len(edge.Platforms) == len(t.platforms) {
+		return ""
+	}
+	return strings.Join(edge.Platforms, "\\n")
+}
@@ -0,0 +1,56 @@
+package tree
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUnion(t *testing.T) {
+	linux := fixtureTree(t, map[string][]string{
+		"a":    {"b", "unix"},
+		"b":    nil,
+		"unix": nil,
+	})
+	windows := fixtureTree(t, map[string][]string{
+		"a":   {"b", "win"},
+		"b":   {"win"},
+		"win": nil,
+	})
+	u := Union(map[string]*Tree{"windows/amd64": windows, "linux/amd64": linux})
+
+	if got, want := u.Platforms(), []string{"linux/amd64", "windows/amd64"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got platforms %v, want %v", got, want)
+	}
+	if got, want := u.PackageNames(), []string{"ex.com/a", "ex.com/b", "ex.com/unix", "ex.com/win"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got packages %v, want %v", got, want)
+	}
+
+	tests := []struct {
+		from, to      string
+		wantPlatforms []string
+		wantLabel     string
+	}{
+		{"ex.com/a", "ex.com/b", []string{"linux/amd64", "windows/amd64"}, ""},
+		{"ex.com/a", "ex.com/unix", []string{"linux/amd64"}, "linux/amd64"},
+		{"ex.com/a", "ex.com/win", []string{"windows/amd64"}, "windows/amd64"},
+		{"ex.com/b", "ex.com/win", []string{"windows/amd64"}, "windows/amd64"},
+	}
+	for _, tt := range tests {
+		edge, ok := u.Edge(tt.from, tt.to)
+		if !ok {
+			t.Errorf("no edge %s -> %s", tt.from, tt.to)
+			continue
+		}
+		if !reflect.DeepEqual(edge.Platforms, tt.wantPlatforms) {
+			t.Errorf("%s -> %s: got platforms %v, want %v", tt.from, tt.to, edge.Platforms, tt.wantPlatforms)
+		}
+		if got := u.platformLabel(edge); got != tt.wantLabel {
+			t.Errorf("%s -> %s: got label %q, want %q", tt.from, tt.to, got, tt.wantLabel)
+		}
+	}
+
+	// the per-platform trees are left alone
+	if edge, _ := linux.Edge("ex.com/a", "ex.com/b"); edge.Platforms != nil {
+		t.Errorf("linux tree's edge gained platforms %v", edge.Platforms)
+	}
+}
@@ -0,0 +1,79 @@
+package tree
+
+import (
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ImportSpec is a single import declaration in one of a package's files.
+type ImportSpec struct {
+	// File is the name of the file, relative to the package's directory.
+	File string
+	// Line is the line of the file the import is on.
+	Line int
+	// Path is the imported package's import path.
+	Path string
+	// Name is the name the package is imported as, if one is given: an
+	// identifier, "_" or ".".
+	Name string
+	// Constraint is the file's //go:build expression, if it has one.
+	Constraint string
+}
+
+// readImportSpecs fills in the package's import specs by parsing the imports of
+// its Go files (including test files). Files that can't be parsed are skipped.
+func readImportSpecs(pkg *Package) {
+	if pkg.Dir == "" {
+		return
+	}
+
+	files := []string{}
+	files = append(files, pkg.GoFiles...)
+	files = append(files, pkg.TestGoFiles...)
+	files = append(files, pkg.XTestGoFiles...)
+
+	specs := []ImportSpec{}
+	fset := token.NewFileSet()
+	for _, file := range files {
+		f, err := parser.ParseFile(fset, filepath.Join(pkg.Dir, file), nil, parser.ImportsOnly|parser.ParseComments)
+		if err != nil {
+			logrus.Debugf("could not parse imports of %s: %v", file, err)
+			continue
+		}
+
+		constraint := ""
+		for _, group := range f.Comments {
+			if group.Pos() >= f.Package {
+				break
+			}
+			for _, c := range group.List {
+				if strings.HasPrefix(c.Text, "//go:build ") {
+					constraint = strings.TrimSpace(strings.TrimPrefix(c.Text, "//go:build "))
+				}
+			}
+		}
+
+		for _, imp := range f.Imports {
+			importPath, err := strconv.Unquote(imp.Path.Value)
+			if err != nil {
+				continue
+			}
+			spec := ImportSpec{
+				File:       file,
+				Line:       fset.Position(imp.Pos()).Line,
+				Path:       importPath,
+				Constraint: constraint,
+			}
+			if imp.Name != nil {
+				spec.Name = imp.Name.Name
+			}
+			specs = append(specs, spec)
+		}
+	}
+	pkg.ImportSpecs = specs
+}
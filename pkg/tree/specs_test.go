@@ -0,0 +1,96 @@
+package tree
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// specsPackage writes a package's files to a temporary directory, and returns
+// the package with its import specs read from them.
+func specsPackage(t *testing.T) *Package {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.go":               "package a\n\nimport (\n\t\"ex.com/b\"\n\t_ \"ex.com/c\"\n)\n",
+		"a_linux.go":         "//go:build linux\n\npackage a\n\nimport . \"ex.com/b\"\n",
+		"bad.go":             "package a\n\nimport (\n",
+		"a_internal_test.go": "package a\n\nimport \"ex.com/d\"\n",
+		"a_test.go":          "package a_test\n\nimport (\n\t\"ex.com/a\"\n\tx \"ex.com/e\"\n)\n",
+	}
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pkg := &Package{
+		ImportPath:   "ex.com/a",
+		Dir:          dir,
+		Name:         "a",
+		GoFiles:      []string{"a.go", "a_linux.go", "bad.go"},
+		TestGoFiles:  []string{"a_internal_test.go"},
+		XTestGoFiles: []string{"a_test.go"},
+		Imports:      []string{"ex.com/b", "ex.com/c"},
+		TestImports:  []string{"ex.com/d"},
+		XTestImports: []string{"ex.com/a", "ex.com/e"},
+	}
+	readImportSpecs(pkg)
+	return pkg
+}
+
+func TestReadImportSpecs(t *testing.T) {
+	want := []ImportSpec{
+		{File: "a.go", Line: 4, Path: "ex.com/b"},
+		{File: "a.go", Line: 5, Path: "ex.com/c", Name: "_"},
+		{File: "a_linux.go", Line: 5, Path: "ex.com/b", Name: ".", Constraint: "linux"},
+		{File: "a_internal_test.go", Line: 3, Path: "ex.com/d"},
+		{File: "a_test.go", Line: 4, Path: "ex.com/a"},
+		{File: "a_test.go", Line: 5, Path: "ex.com/e", Name: "x"},
+	}
+	if got := specsPackage(t).ImportSpecs; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestEdgeFacts(t *testing.T) {
+	loader := NewFixtureLoader(
+		specsPackage(t),
+		&Package{ImportPath: "ex.com/b"},
+		&Package{ImportPath: "ex.com/c"},
+		&Package{ImportPath: "ex.com/d"},
+		&Package{ImportPath: "ex.com/e"},
+	)
+	tests := []struct {
+		tests bool
+		want  []Edge
+	}{
+		{
+			tests: false,
+			want: []Edge{
+				{From: "ex.com/a", To: "ex.com/b", Files: []string{"a.go", "a_linux.go"}, Dot: true, Constraints: []string{"linux"}},
+				{From: "ex.com/a", To: "ex.com/c", Files: []string{"a.go"}, Blank: true},
+			},
+		},
+		{
+			tests: true,
+			want: []Edge{
+				{From: "ex.com/a", To: "ex.com/b", Files: []string{"a.go", "a_linux.go"}, Dot: true, Constraints: []string{"linux"}},
+				{From: "ex.com/a", To: "ex.com/c", Files: []string{"a.go"}, Blank: true},
+				{From: "ex.com/a", To: "ex.com/d", Test: true, Files: []string{"a_internal_test.go"}},
+				{From: "ex.com/a_test", To: "ex.com/a", Test: true, Files: []string{"a_test.go"}},
+				{From: "ex.com/a_test", To: "ex.com/e", Test: true, Files: []string{"a_test.go"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		tr := NewTree("ex.com", loader)
+		tr.SetIncludeTests(tt.tests)
+		if _, err := tr.AddRecursive("a"); err != nil {
+			t.Fatal(err)
+		}
+		if got := tr.Broaden(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("tests %v: got\n%v\nwant\n%v", tt.tests, got, tt.want)
+		}
+	}
+}
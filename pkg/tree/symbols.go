@@ -0,0 +1,175 @@
+package tree
+
+import (
+	"fmt"
+	"go/build"
+	"go/token"
+	"go/types"
+	"sort"
+	"strings"
+)
+
+// Symbol is an exported identifier of one package that another package uses:
+// a package-level name like "NewTree", or a method like "Tree.Grow".
+type Symbol struct {
+	Name string `json:"name"`
+	// Uses is how many times the importing package refers to it.
+	Uses int `json:"uses"`
+}
+
+// LoadSymbols type-checks the receiver's packages, and records on each of
+// their imports which of the imported package's exported identifiers they use
+// (see Edge.Symbols). Packages are loaded by the go command, run in the given
+// directory for the given build context (or the go command's defaults, if ctx
+// is nil). If the receiver includes tests, test files are checked too.
+//
+// Type-checking is much slower than listing imports, so this is only done on
+// request.
+func (t *Tree) LoadSymbols(dir string, ctx *build.Context) error {
+	pkgs, err := t.typeCheck(dir, ctx)
+	if err != nil {
+		return err
+	}
+	for path, pkg := range pkgs {
+		leaf, ok := t.packageMap[path]
+		if !ok || leaf == nil {
+			continue
+		}
+		leaf.recordSymbols(pkg.Types, pkg.TypesInfo, pkg.Fset)
+	}
+	return nil
+}
+
+// recordSymbols sets the symbols of each of the receiver's imports, from the
+// type information of its package. Uses in test files are only counted on
+// test-only imports, and the rest only on the others.
+func (l *Leaf) recordSymbols(pkg *types.Package, info *types.Info, fset *token.FileSet) {
+	// by whether they're in a test file, then import path
+	uses := map[bool]map[string]map[string]int{false: {}, true: {}}
+	for id, obj := range info.Uses {
+		name, ok := symbolName(obj, pkg)
+		if !ok {
+			continue
+		}
+		test := strings.HasSuffix(fset.Position(id.Pos()).Filename, "_test.go")
+		path := obj.Pkg().Path()
+		if uses[test][path] == nil {
+			uses[test][path] = make(map[string]int)
+		}
+		uses[test][path][name]++
+	}
+
+	// copies of the leaf share its edges
+	deps := make([]Edge, len(l.deps))
+	copy(deps, l.deps)
+	l.deps = deps
+	for i := range l.deps {
+		e := &l.deps[i]
+		e.Symbols = []Symbol{}
+		for name, count := range uses[e.Test][e.To] {
+			e.Symbols = append(e.Symbols, Symbol{Name: name, Uses: count})
+		}
+		sortSymbols(e.Symbols)
+	}
+}
+
+// symbolName returns the name to record a use of the object by, if it is an
+// exported package-level object or method of a package other than the given
+// one.
+func symbolName(obj types.Object, from *types.Package) (string, bool) {
+	if obj.Pkg() == nil || obj.Pkg() == from || !obj.Exported() {
+		return "", false
+	}
+	if obj.Parent() == obj.Pkg().Scope() {
+		return obj.Name(), true
+	}
+	fn, ok := obj.(*types.Func)
+	if !ok {
+		return "", false
+	}
+	recv := fn.Type().(*types.Signature).Recv()
+	if recv == nil {
+		return "", false
+	}
+	t := recv.Type()
+	if p, ok := t.(*types.Pointer); ok {
+		t = p.Elem()
+	}
+	named, ok := t.(*types.Named)
+	if !ok {
+		// a method of an unnamed interface
+		return "", false
+	}
+	return named.Obj().Name() + "." + fn.Name(), true
+}
+
+// sortSymbols sorts symbols by how often they're used, most first, then by
+// name.
+func sortSymbols(symbols []Symbol) {
+	sort.Slice(symbols, func(i, j int) bool {
+		if symbols[i].Uses != symbols[j].Uses {
+			return symbols[i].Uses > symbols[j].Uses
+		}
+		return symbols[i].Name < symbols[j].Name
+	})
+}
+
+// mergeSymbols returns the symbols of both lists, adding up the uses of those
+// with the same name. If neither list is known, neither is the result.
+func mergeSymbols(a, b []Symbol) []Symbol {
+	if a == nil && b == nil {
+		return nil
+	}
+	uses := make(map[string]int)
+	for _, s := range append(append([]Symbol{}, a...), b...) {
+		uses[s.Name] += s.Uses
+	}
+	merged := make([]Symbol, 0, len(uses))
+	for name, count := range uses {
+		merged = append(merged, Symbol{Name: name, Uses: count})
+	}
+	sortSymbols(merged)
+	return merged
+}
+
+// symbolUses returns how many times the edge's symbols are used, in all.
+func symbolUses(edge Edge) int {
+	uses := 0
+	for _, s := range edge.Symbols {
+		uses += s.Uses
+	}
+	return uses
+}
+
+// symbolLabel summarizes the symbols an edge uses, for labelling it: the three
+// most used, and how many more there are.
+func symbolLabel(edge Edge) string {
+	const shown = 3
+	names := []string{}
+	for i, s := range edge.Symbols {
+		if i == shown {
+			names = append(names, fmt.Sprintf("+%d more", len(edge.Symbols)-shown))
+			break
+		}
+		names = append(names, s.Name)
+	}
+	return strings.Join(names, "\\n")
+}
+
+// EdgeDetail lists each of the receiver's imports, with the symbols it uses
+// and how often, as recorded by LoadSymbols.
+func (t *Tree) EdgeDetail() string {
+	b := strings.Builder{}
+	for _, edge := range t.Broaden() {
+		fmt.Fprintf(&b, "%s -> %s", t.DisplayName(edge.From), t.DisplayName(edge.To))
+		if edge.Symbols == nil {
+			b.WriteString(" (symbols unknown)\n")
+			continue
+		}
+		fmt.Fprintf(&b, " (%d symbols, %d uses)\n", len(edge.Symbols), symbolUses(edge))
+		for _, s := range edge.Symbols {
+			fmt.Fprintf(&b, "    %s %d\n", s.Name, s.Uses)
+		}
+	}
+	return b.String()
+}
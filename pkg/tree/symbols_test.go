@@ -0,0 +1,178 @@
+package tree
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"reflect"
+	"testing"
+)
+
+// checkSources type-checks a package from the given files' sources, importing
+// the given packages by path.
+func checkSources(t *testing.T, fset *token.FileSet, path string, files map[string]string, imports ...*types.Package) (*types.Package, *types.Info) {
+	t.Helper()
+	parsed := []*ast.File{}
+	for name, src := range files {
+		f, err := parser.ParseFile(fset, name, src, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parsed = append(parsed, f)
+	}
+	byPath := map[string]*types.Package{}
+	for _, pkg := range imports {
+		byPath[pkg.Path()] = pkg
+	}
+	conf := types.Config{Importer: importerFunc(func(path string) (*types.Package, error) {
+		if pkg, ok := byPath[path]; ok {
+			return pkg, nil
+		}
+		return nil, fmt.Errorf("no package %s", path)
+	})}
+	info := &types.Info{Uses: map[*ast.Ident]types.Object{}}
+	pkg, err := conf.Check(path, fset, parsed, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pkg, info
+}
+
+type importerFunc func(path string) (*types.Package, error)
+
+func (f importerFunc) Import(path string) (*types.Package, error) { return f(path) }
+
+const symbolsB = `package b
+
+type T struct{}
+
+func (*T) M() {}
+
+func New() *T { return nil }
+
+var V, unexported int
+
+type I interface{ M() }
+
+type S struct{ F int }
+`
+
+func TestSymbolName(t *testing.T) {
+	fset := token.NewFileSet()
+	b, _ := checkSources(t, fset, "ex.com/b", map[string]string{"b.go": symbolsB})
+	a, _ := checkSources(t, fset, "ex.com/a", map[string]string{
+		"a.go": "package a\n\nvar X interface{ M() }\n",
+	})
+
+	method := func(pkg *types.Package, typ, name string) types.Object {
+		obj, _, _ := types.LookupFieldOrMethod(pkg.Scope().Lookup(typ).Type(), true, pkg, name)
+		return obj
+	}
+	unnamed, _, _ := types.LookupFieldOrMethod(a.Scope().Lookup("X").Type(), false, a, "M")
+
+	tests := []struct {
+		name   string
+		obj    types.Object
+		from   *types.Package
+		want   string
+		wantOK bool
+	}{
+		{"package-level func", b.Scope().Lookup("New"), a, "New", true},
+		{"package-level var", b.Scope().Lookup("V"), a, "V", true},
+		{"package-level type", b.Scope().Lookup("T"), a, "T", true},
+		{"method on a pointer receiver", method(b, "T", "M"), a, "T.M", true},
+		{"interface method", method(b, "I", "M"), a, "I.M", true},
+		{"unnamed interface method", unnamed, b, "", false},
+		{"unexported", b.Scope().Lookup("unexported"), a, "", false},
+		{"same package", b.Scope().Lookup("New"), b, "", false},
+		{"predeclared", types.Universe.Lookup("len"), a, "", false},
+		{"field", b.Scope().Lookup("S").Type().Underlying().(*types.Struct).Field(0), a, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := symbolName(tt.obj, tt.from)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("got %q, %v, want %q, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestRecordSymbols(t *testing.T) {
+	fset := token.NewFileSet()
+	b, _ := checkSources(t, fset, "ex.com/b", map[string]string{"b.go": symbolsB})
+	c, _ := checkSources(t, fset, "ex.com/c", map[string]string{"c.go": "package c\n\nconst C = 1\n"})
+	// the test variant of a, with its test file
+	a, info := checkSources(t, fset, "ex.com/a", map[string]string{
+		"/src/a/a.go":      "package a\n\nimport \"ex.com/b\"\n\nvar x = b.New()\n\nfunc f() { x.M(); x.M() }\n",
+		"/src/a/a_test.go": "package a\n\nimport (\n\t\"ex.com/b\"\n\t\"ex.com/c\"\n)\n\nvar y = b.V + c.C\n",
+	}, b, c)
+
+	shared := []Edge{
+		{From: "ex.com/a", To: "ex.com/b"},
+		{From: "ex.com/a", To: "ex.com/c", Test: true},
+	}
+	leaf := &Leaf{deps: shared}
+	leaf.recordSymbols(a, info, fset)
+
+	want := []Edge{
+		// b.V is only used by the test file
+		{From: "ex.com/a", To: "ex.com/b", Symbols: []Symbol{{"T.M", 2}, {"New", 1}}},
+		{From: "ex.com/a", To: "ex.com/c", Test: true, Symbols: []Symbol{{"C", 1}}},
+	}
+	if !reflect.DeepEqual(leaf.deps, want) {
+		t.Errorf("got %v, want %v", leaf.deps, want)
+	}
+	if shared[0].Symbols != nil {
+		t.Errorf("edges shared with a copy of the leaf gained symbols %v", shared[0].Symbols)
+	}
+}
+
+func TestMergeSymbols(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []Symbol
+		want []Symbol
+	}{
+		{"both unknown", nil, nil, nil},
+		{"one unknown", []Symbol{{"A", 1}}, nil, []Symbol{{"A", 1}}},
+		{"none used", []Symbol{}, nil, []Symbol{}},
+		{
+			name: "added up and sorted",
+			a:    []Symbol{{"A", 1}, {"B", 2}},
+			b:    []Symbol{{"A", 2}, {"C", 3}},
+			want: []Symbol{{"A", 3}, {"C", 3}, {"B", 2}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergeSymbols(tt.a, tt.b); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEdgeDetail(t *testing.T) {
+	tr := fixtureTree(t, map[string][]string{
+		"a": {"b", "c", "d"},
+		"b": nil,
+		"c": nil,
+		"d": nil,
+	})
+	deps := tr.packageMap["ex.com/a"].deps
+	deps[0].Symbols = []Symbol{{"New", 3}, {"Tree.Grow", 1}}
+	deps[1].Symbols = []Symbol{}
+
+	want := `a -> b (2 symbols, 4 uses)
+    New 3
+    Tree.Grow 1
+a -> c (0 symbols, 0 uses)
+a -> d (symbols unknown)
+`
+	if got := tr.EdgeDetail(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
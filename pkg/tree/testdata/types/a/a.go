@@ -0,0 +1,23 @@
+package a
+
+import "ex.com/types/b"
+
+type Tree struct {
+	b.Base
+	leaves []*Leaf
+	index  map[string]b.Node
+	cache  *cache
+}
+
+func (t *Tree) Find(n b.Node) (*Leaf, error) { return nil, nil }
+
+type Leaf struct{ parent *Tree }
+
+type Walker interface {
+	b.Visitor
+	Walk(*Tree) bool
+}
+
+type leafSet []Leaf
+
+type cache struct{}
@@ -0,0 +1,5 @@
+package a
+
+import "ex.com/types/b"
+
+func (Leaf) helper() b.Helper { return b.Helper{} }
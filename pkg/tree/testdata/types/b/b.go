@@ -0,0 +1,9 @@
+package b
+
+type Base struct{}
+
+type Node interface{ ID() string }
+
+type Visitor interface{ Visit(Node) }
+
+type Helper struct{}
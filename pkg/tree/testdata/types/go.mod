@@ -0,0 +1,3 @@
+module ex.com/types
+
+go 1.21
@@ -13,13 +13,29 @@ type Tree struct {
 	parentDirectory string
 	includeTests    bool
 	includeExts     bool
+	loader          Loader
+	jobs            int
+	platforms       []string
+	legend          bool
+	cluster         bool
+	colorBy         string
+	sizeBy          string
+	coverage        map[string]float64
+	filter          func(importPath string) bool
+	// types is whether the tree's "packages" are types (see Tree.Types)
+	types bool
 }
 
-// NewTree returns a new, empty Tree
-func NewTree(parentDirectory string) *Tree {
+// NewTree returns a new, empty Tree, which will use the given loader to
+// resolve packages. If loader is nil, a GOPATHLoader is used.
+func NewTree(parentDirectory string, loader Loader) *Tree {
+	if loader == nil {
+		loader = NewGOPATHLoader(nil)
+	}
 	t := Tree{
 		packageMap:      make(map[string]*Leaf),
 		parentDirectory: path.Clean(parentDirectory),
+		loader:          loader,
 	}
 
 	return &t
@@ -38,3 +54,56 @@ func (t *Tree) SetIncludeExts(includeExts bool) {
 	logrus.Debugf("tree include exts? %v", includeExts)
 	t.includeExts = includeExts
 }
+
+// SetJobs sets how many packages the receiver may load at once. A count of zero
+// or less means one per CPU.
+func (t *Tree) SetJobs(jobs int) {
+	logrus.Debugf("tree jobs: %d", jobs)
+	t.jobs = jobs
+}
+
+// SetFilter sets a predicate that decides which packages the receiver may
+// include, by import path. Packages it rejects are neither loaded nor drawn,
+// and nor are imports of them. A nil filter includes every package.
+func (t *Tree) SetFilter(filter func(importPath string) bool) {
+	t.filter = filter
+}
+
+// SetLegend modifies the receiver to include or exclude a legend in its
+// Graphviz output.
+func (t *Tree) SetLegend(legend bool) {
+	logrus.Debugf("tree legend? %v", legend)
+	t.legend = legend
+}
+
+// SetCluster modifies the receiver to group the nodes of its Graphviz output
+// into clusters, nested like the directories holding the packages.
+func (t *Tree) SetCluster(cluster bool) {
+	logrus.Debugf("tree cluster? %v", cluster)
+	t.cluster = cluster
+}
+
+// DisplayName returns the display name of the named package, or the name
+// itself if the receiver doesn't have that package.
+func (t *Tree) DisplayName(name string) string {
+	leaf, ok := t.packageMap[t.resolve(name)]
+	if !ok || leaf == nil {
+		return name
+	}
+	return leaf.displayName
+}
+
+// ParentDirectory returns the receiver's parent directory.
+func (t *Tree) ParentDirectory() string {
+	return t.parentDirectory
+}
+
+// Package returns what the receiver's loader knows about the named package, or
+// nil if the receiver doesn't have that package or it is broken.
+func (t *Tree) Package(name string) *Package {
+	leaf, ok := t.packageMap[t.resolve(name)]
+	if !ok || leaf == nil {
+		return nil
+	}
+	return leaf.pkg
+}
@@ -0,0 +1,71 @@
+package tree
+
+import (
+	"fmt"
+	"go/build"
+	"os"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/tools/go/packages"
+)
+
+// typeCheck loads and type-checks the receiver's packages with the go command,
+// run in the given directory for the given build context (or the go command's
+// defaults, if ctx is nil). If the receiver includes tests, their files are
+// checked too. It returns the packages by import path; external test packages
+// are named like their leaves, "foo_test".
+func (t *Tree) typeCheck(dir string, ctx *build.Context) (map[string]*packages.Package, error) {
+	patterns := []string{}
+	for _, name := range t.sortedNames() {
+		leaf := t.packageMap[name]
+		if leaf == nil || leaf.xtest || leaf.IsBroken() {
+			continue
+		}
+		patterns = append(patterns, leaf.pkg.ImportPath)
+	}
+	if len(patterns) == 0 {
+		return map[string]*packages.Package{}, nil
+	}
+
+	cfg := &packages.Config{
+		Mode:  packages.NeedName | packages.NeedFiles | packages.NeedSyntax | packages.NeedTypes | packages.NeedTypesInfo,
+		Dir:   dir,
+		Tests: t.includeTests,
+		Env:   os.Environ(),
+		Logf:  logrus.Debugf,
+	}
+	if gomod, err := ModuleFile(dir); err == nil && gomod == "" {
+		cfg.Env = append(cfg.Env, "GO111MODULE=off")
+	}
+	if ctx != nil {
+		cfg.Env = append(cfg.Env, "GOOS="+ctx.GOOS, "GOARCH="+ctx.GOARCH)
+		if len(ctx.BuildTags) > 0 {
+			cfg.BuildFlags = []string{"-tags", strings.Join(ctx.BuildTags, ",")}
+		}
+	}
+	logrus.Infof("Type-checking %d packages", len(patterns))
+	pkgs, err := packages.Load(cfg, patterns...)
+	if err != nil {
+		return nil, fmt.Errorf("could not type-check packages: %v", err)
+	}
+
+	// with tests, each package is loaded twice; the variant compiled for its
+	// tests has everything the plain one has, and more
+	chosen := make(map[string]*packages.Package)
+	for _, pkg := range pkgs {
+		if strings.HasSuffix(pkg.PkgPath, ".test") || pkg.TypesInfo == nil {
+			continue
+		}
+		if _, ok := chosen[pkg.PkgPath]; ok && !strings.Contains(pkg.ID, " [") {
+			continue
+		}
+		chosen[pkg.PkgPath] = pkg
+	}
+	for path, pkg := range chosen {
+		for _, err := range pkg.Errors {
+			logrus.Debugf("%s: %v", path, err)
+		}
+	}
+	return chosen, nil
+}
@@ -0,0 +1,227 @@
+package tree
+
+import (
+	"fmt"
+	"go/build"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Types returns a new tree of the named types declared in the receiver's
+// packages. Each "package" of the new tree is a type, named like
+// "github.com/spilliams/goraffe/pkg/tree.Tree", and each "import" is a type it
+// refers to in its fields, embedded types, underlying type or method
+// signatures (see Edge.Via). The types of the receiver's root packages are the
+// new tree's roots. References made only in test files are test imports.
+//
+// The packages are type-checked like they are for LoadSymbols. If exported is
+// true, only exported types are included.
+//
+// The new tree may be kept, grown and pruned like any other.
+func (t *Tree) Types(dir string, ctx *build.Context, exported bool) (*Tree, error) {
+	pkgs, err := t.typeCheck(dir, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	loader := NewFixtureLoader()
+	roots := []string{}
+	vias := make(map[string]map[string][]string)
+	paths := make([]string, 0, len(pkgs))
+	for path := range pkgs {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		pkg := pkgs[path]
+		leaf := t.packageMap[path]
+		if leaf == nil {
+			continue
+		}
+		scope := pkg.Types.Scope()
+		for _, name := range scope.Names() {
+			tn, ok := scope.Lookup(name).(*types.TypeName)
+			if !ok || tn.IsAlias() || exported && !tn.Exported() {
+				continue
+			}
+			from := typeName(tn)
+			typePkg := &Package{
+				ImportPath: from,
+				Dir:        filepath.Dir(pkg.Fset.Position(tn.Pos()).Filename),
+				Name:       tn.Name(),
+			}
+			vias[from] = make(map[string][]string)
+			for _, dep := range namedTypeDeps(tn) {
+				obj := dep.obj
+				if obj.Pkg() == nil || obj.Parent() != obj.Pkg().Scope() {
+					// a predeclared type, or one declared in a function
+					continue
+				}
+				to := typeName(obj)
+				if to == from {
+					continue
+				}
+				if _, ok := pkgs[obj.Pkg().Path()]; !ok || exported && !obj.Exported() {
+					continue
+				}
+				file := filepath.Base(pkg.Fset.Position(dep.pos).Filename)
+				if strings.HasSuffix(file, "_test.go") {
+					typePkg.TestImports = appendUnique(typePkg.TestImports, to)
+					typePkg.TestGoFiles = appendUnique(typePkg.TestGoFiles, file)
+				} else {
+					typePkg.Imports = appendUnique(typePkg.Imports, to)
+					typePkg.GoFiles = appendUnique(typePkg.GoFiles, file)
+				}
+				typePkg.ImportSpecs = append(typePkg.ImportSpecs, ImportSpec{File: file, Path: to})
+				if !contains(vias[from][to], dep.via) {
+					vias[from][to] = append(vias[from][to], dep.via)
+				}
+			}
+			loader.Add(typePkg)
+			if leaf.root {
+				roots = append(roots, from)
+			}
+		}
+	}
+
+	tt := NewTree(t.parentDirectory, loader)
+	tt.includeTests = t.includeTests
+	tt.includeExts = t.includeExts
+	tt.jobs = t.jobs
+	tt.types = true
+	for _, root := range roots {
+		if _, err := tt.AddRecursive(root); err != nil {
+			return nil, err
+		}
+	}
+	for _, leaf := range tt.packageMap {
+		for i := range leaf.deps {
+			leaf.deps[i].Via = vias[leaf.deps[i].From][leaf.deps[i].To]
+		}
+	}
+	return tt, nil
+}
+
+// typeName returns the name a type tree knows a named type by.
+func typeName(tn *types.TypeName) string {
+	return fmt.Sprintf("%s.%s", tn.Pkg().Path(), tn.Name())
+}
+
+// typeDep is one way a named type refers to another: through a field, say, at
+// the given position.
+type typeDep struct {
+	obj *types.TypeName
+	via string
+	pos token.Pos
+}
+
+// namedTypeDeps returns the named types a named type refers to: in its fields
+// (or embedded types), in its interface methods, in its underlying type if it
+// is neither a struct nor an interface, and in its methods' signatures.
+func namedTypeDeps(tn *types.TypeName) []typeDep {
+	deps := []typeDep{}
+	add := func(t types.Type, via string, pos token.Pos) {
+		typeRefs(t, func(obj *types.TypeName) {
+			deps = append(deps, typeDep{obj: obj, via: via, pos: pos})
+		})
+	}
+	addSignature := func(m *types.Func) {
+		sig := m.Type().(*types.Signature)
+		add(sig.Params(), "method "+m.Name()+" param", m.Pos())
+		add(sig.Results(), "method "+m.Name()+" result", m.Pos())
+	}
+
+	named, ok := tn.Type().(*types.Named)
+	if !ok {
+		return deps
+	}
+	switch u := named.Underlying().(type) {
+	case *types.Struct:
+		for i := 0; i < u.NumFields(); i++ {
+			f := u.Field(i)
+			via := "field " + f.Name()
+			if f.Embedded() {
+				via = "embeds"
+			}
+			add(f.Type(), via, f.Pos())
+		}
+	case *types.Interface:
+		for i := 0; i < u.NumEmbeddeds(); i++ {
+			add(u.EmbeddedType(i), "embeds", tn.Pos())
+		}
+		for i := 0; i < u.NumExplicitMethods(); i++ {
+			addSignature(u.ExplicitMethod(i))
+		}
+	default:
+		add(u, "underlying", tn.Pos())
+	}
+	for i := 0; i < named.NumMethods(); i++ {
+		addSignature(named.Method(i))
+	}
+	return deps
+}
+
+// typeRefs calls visit with each named type that the given type is made of.
+// Named types are not looked into, except for their type arguments.
+func typeRefs(t types.Type, visit func(*types.TypeName)) {
+	switch t := t.(type) {
+	case *types.Named:
+		visit(t.Origin().Obj())
+		args := t.TypeArgs()
+		for i := 0; i < args.Len(); i++ {
+			typeRefs(args.At(i), visit)
+		}
+	case *types.Alias:
+		typeRefs(types.Unalias(t), visit)
+	case *types.Pointer:
+		typeRefs(t.Elem(), visit)
+	case *types.Slice:
+		typeRefs(t.Elem(), visit)
+	case *types.Array:
+		typeRefs(t.Elem(), visit)
+	case *types.Chan:
+		typeRefs(t.Elem(), visit)
+	case *types.Map:
+		typeRefs(t.Key(), visit)
+		typeRefs(t.Elem(), visit)
+	case *types.Signature:
+		typeRefs(t.Params(), visit)
+		typeRefs(t.Results(), visit)
+	case *types.Tuple:
+		for i := 0; i < t.Len(); i++ {
+			typeRefs(t.At(i).Type(), visit)
+		}
+	case *types.Struct:
+		for i := 0; i < t.NumFields(); i++ {
+			typeRefs(t.Field(i).Type(), visit)
+		}
+	case *types.Interface:
+		for i := 0; i < t.NumEmbeddeds(); i++ {
+			typeRefs(t.EmbeddedType(i), visit)
+		}
+		for i := 0; i < t.NumExplicitMethods(); i++ {
+			typeRefs(t.ExplicitMethod(i).Type(), visit)
+		}
+	}
+}
+
+// viaLabel summarizes how one type refers to another, for labelling their
+// edge: the first three ways, and how many more there are.
+func viaLabel(edge Edge) string {
+	const shown = 3
+	if len(edge.Via) <= shown {
+		return strings.Join(edge.Via, "\\n")
+	}
+	return strings.Join(edge.Via[:shown], "\\n") + fmt.Sprintf("\\n+%d more", len(edge.Via)-shown)
+}
+
+// appendUnique appends s to the list, unless the list already has it.
+func appendUnique(list []string, s string) []string {
+	if contains(list, s) {
+		return list
+	}
+	return append(list, s)
+}
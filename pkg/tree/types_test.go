@@ -0,0 +1,104 @@
+package tree
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTypes(t *testing.T) {
+	const (
+		a = "ex.com/types/a."
+		b = "ex.com/types/b."
+	)
+	tests := []struct {
+		name     string
+		tests    bool
+		exported bool
+		want     []string
+	}{
+		{
+			name: "all",
+			want: []string{
+				a + "Leaf -> " + a + "Tree (files: a.go, via: field parent)",
+				a + "Tree -> " + a + "Leaf (files: a.go, via: field leaves; method Find result)",
+				a + "Tree -> " + a + "cache (files: a.go, via: field cache)",
+				a + "Tree -> " + b + "Base (files: a.go, via: embeds)",
+				a + "Tree -> " + b + "Node (files: a.go, via: field index; method Find param)",
+				a + "Walker -> " + a + "Tree (files: a.go, via: method Walk param)",
+				a + "Walker -> " + b + "Visitor (files: a.go, via: embeds)",
+				a + "leafSet -> " + a + "Leaf (files: a.go, via: underlying)",
+				b + "Visitor -> " + b + "Node (files: b.go, via: method Visit param)",
+			},
+		},
+		{
+			name:     "exported",
+			exported: true,
+			want: []string{
+				a + "Leaf -> " + a + "Tree (files: a.go, via: field parent)",
+				a + "Tree -> " + a + "Leaf (files: a.go, via: field leaves; method Find result)",
+				a + "Tree -> " + b + "Base (files: a.go, via: embeds)",
+				a + "Tree -> " + b + "Node (files: a.go, via: field index; method Find param)",
+				a + "Walker -> " + a + "Tree (files: a.go, via: method Walk param)",
+				a + "Walker -> " + b + "Visitor (files: a.go, via: embeds)",
+				b + "Visitor -> " + b + "Node (files: b.go, via: method Visit param)",
+			},
+		},
+		{
+			// Leaf's helper method is only declared in a test file
+			name:     "tests",
+			tests:    true,
+			exported: true,
+			want: []string{
+				a + "Leaf -> " + a + "Tree (files: a.go, via: field parent)",
+				a + "Leaf -> " + b + "Helper (test, files: a_test.go, via: method helper result)",
+				a + "Tree -> " + a + "Leaf (files: a.go, via: field leaves; method Find result)",
+				a + "Tree -> " + b + "Base (files: a.go, via: embeds)",
+				a + "Tree -> " + b + "Node (files: a.go, via: field index; method Find param)",
+				a + "Walker -> " + a + "Tree (files: a.go, via: method Walk param)",
+				a + "Walker -> " + b + "Visitor (files: a.go, via: embeds)",
+				b + "Visitor -> " + b + "Node (files: b.go, via: method Visit param)",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := NewTree("ex.com/types", NewFixtureLoader(
+				&Package{ImportPath: "ex.com/types/a", Imports: []string{"ex.com/types/b"}},
+				&Package{ImportPath: "ex.com/types/b"},
+			))
+			tr.SetIncludeTests(tt.tests)
+			if _, err := tr.AddRecursive("a"); err != nil {
+				t.Fatal(err)
+			}
+
+			types, err := tr.Types("testdata/types", nil, tt.exported)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []string{}
+			for _, edge := range types.Broaden() {
+				got = append(got, edge.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestViaLabel(t *testing.T) {
+	tests := []struct {
+		via  []string
+		want string
+	}{
+		{nil, ""},
+		{[]string{"embeds"}, "embeds"},
+		{[]string{"field a", "field b", "field c"}, `field a\nfield b\nfield c`},
+		{[]string{"field a", "field b", "field c", "field d", "field e"}, `field a\nfield b\nfield c\n+2 more`},
+	}
+	for _, tt := range tests {
+		if got := viaLabel(Edge{Via: tt.via}); got != tt.want {
+			t.Errorf("viaLabel(%v): got %q, want %q", tt.via, got, tt.want)
+		}
+	}
+}